@@ -10,7 +10,7 @@ import (
 )
 
 func Registry() *task.Registry {
-	registry := task.NewRegistry(task.WithAutoNamespaces(true))
+	registry := task.NewRegistry(task.WithAutoNamespaces(true), task.WithRunHistory(10))
 	registry.Declare("build").Description("build the goke build script").DependsOn("clean", "sa").Do(Build)
 	registry.Declare("clean").Description("cleans up the artifacts").Do(Clean)
 	registry.Declare("sa:lint").Description("lint the packages").Do(Lint)
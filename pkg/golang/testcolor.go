@@ -4,19 +4,25 @@ import (
 	"io"
 
 	"github.com/craiggwilson/goke/pkg/editor"
-
-	"github.com/mgutz/ansi"
+	"github.com/craiggwilson/goke/task"
 )
 
+// ColoredTestWriter colors the output of go test. When w is a task.Context, the colors follow its
+// UI, leaving the output uncolored when colors are disabled; otherwise the default theme is used.
 func ColoredTestWriter(w io.Writer) *editor.Writer {
+	ui := task.NewTUI(task.DefaultTheme())
+	if ctx, ok := w.(*task.Context); ok {
+		ui = ctx.UI
+	}
+
 	return editor.New(
 		w,
-		editor.Replace(`(?m)^ok.*`, editor.LineEditorFunc(ansi.ColorFunc("green+b"))),
-		editor.Replace(`(?m)^PASS.*`, editor.LineEditorFunc(ansi.ColorFunc("green+b"))),
-		editor.Replace(`(?m)^(\s*)--- PASS.*`, editor.LineEditorFunc(ansi.ColorFunc("green+b"))),
-		editor.Replace(`(?m)^\?.*`, editor.LineEditorFunc(ansi.ColorFunc("grey+b"))),
-		editor.Replace(`(?m)^FAIL.*`, editor.LineEditorFunc(ansi.ColorFunc("red+b"))),
-		editor.Replace(`(?m)^(\s*)--- FAIL:.*`, editor.LineEditorFunc(ansi.ColorFunc("red+b"))),
+		editor.Replace(`(?m)^ok.*`, editor.LineEditorFunc(ui.Success)),
+		editor.Replace(`(?m)^PASS.*`, editor.LineEditorFunc(ui.Success)),
+		editor.Replace(`(?m)^(\s*)--- PASS.*`, editor.LineEditorFunc(ui.Success)),
+		editor.Replace(`(?m)^\?.*`, editor.LineEditorFunc(ui.Lowlight)),
+		editor.Replace(`(?m)^FAIL.*`, editor.LineEditorFunc(ui.Error)),
+		editor.Replace(`(?m)^(\s*)--- FAIL:.*`, editor.LineEditorFunc(ui.Error)),
 		editor.Remove(`(?m)^(\s*)=== .*`),
 	)
 }
@@ -17,5 +17,5 @@ func LogCmd(ctx *task.Context, cmd *exec.Cmd) {
 		}
 	}
 
-	ctx.Logf("exec: '%s %s'\n", cmd.Path, strings.Join(args, " "))
+	ctx.Logf("exec: '%s'\n", ctx.UI.Command(cmd.Path+" "+strings.Join(args, " ")))
 }
@@ -1,5 +1,7 @@
 package task
 
+import "fmt"
+
 // build begins building a task.
 func build(name string) *Builder {
 	task := &declaredTask{
@@ -25,6 +27,19 @@ func (b *Builder) Arg(name string, validator ...Validator) *Builder {
 	return b.OptionalArg(name)
 }
 
+// DefaultArg declares an argument for the task which takes the given value when not supplied.
+func (b *Builder) DefaultArg(name string, value string, validator ...Validator) *Builder {
+	arg := DeclaredTaskArg{
+		Name:    name,
+		Default: value,
+	}
+	if len(validator) != 0 {
+		arg.Validator = ChainValidator(validator...)
+	}
+	b.task.declaredArgs = append(b.task.declaredArgs, arg)
+	return b
+}
+
 // OptionalArg declares an optional argument for the task.
 func (b *Builder) OptionalArg(name string) *Builder {
 	b.task.declaredArgs = append(b.task.declaredArgs, DeclaredTaskArg{
@@ -58,18 +73,56 @@ func (b *Builder) RequiredArgs(names ...string) *Builder {
 	return b
 }
 
+// SecretArg declares an optional argument for the task whose value, whether supplied as an argument
+// or read from the environment, is masked in all output. An argument which is already declared
+// is marked as secret instead.
+func (b *Builder) SecretArg(name string, validator ...Validator) *Builder {
+	for i := range b.task.declaredArgs {
+		if b.task.declaredArgs[i].Name == name {
+			b.task.declaredArgs[i].Secret = true
+			return b
+		}
+	}
+
+	b.Arg(name, validator...)
+	b.task.declaredArgs[len(b.task.declaredArgs)-1].Secret = true
+	return b
+}
+
+// Alias declares alternate names under which the task can be invoked.
+func (b *Builder) Alias(names ...string) *Builder {
+	b.task.aliases = append(b.task.aliases, names...)
+	return b
+}
+
 // ContinueOnError declares that a task should not stop the build from continuing.
 func (b *Builder) ContinueOnError() *Builder {
 	b.task.continueOnError = true
 	return b
 }
 
+// Deprecated marks the task as deprecated. The task still runs, but a warning
+// pointing to the replacement is printed.
+func (b *Builder) Deprecated(message string, replacement string) *Builder {
+	b.task.deprecation = &Deprecation{
+		Message:     message,
+		Replacement: replacement,
+	}
+	return b
+}
+
 // Description sets the description for the task.
 func (b *Builder) Description(description string) *Builder {
 	b.task.description = description
 	return b
 }
 
+// LongDescription sets the detailed description for the task shown by `help <task>`.
+func (b *Builder) LongDescription(description string) *Builder {
+	b.task.longDescription = description
+	return b
+}
+
 // DependsOn declares other tasks which must run before this one.
 func (b *Builder) DependsOn(names ...string) *Builder {
 	b.task.dependencies = names
@@ -79,6 +132,63 @@ func (b *Builder) DependsOn(names ...string) *Builder {
 // Do declares the executor when this task runs.
 func (b *Builder) Do(executor Executor) {
 	b.task.executor = executor
+	b.task.completed = true
+}
+
+// Aggregate completes a task which only groups its dependencies and has no executor of its own.
+func (b *Builder) Aggregate() {
+	b.task.aggregate = true
+	b.task.completed = true
+}
+
+// Wrap declares middlewares applied around the task's executor. Middlewares registered
+// on the Registry with Use are applied around these.
+func (b *Builder) Wrap(middlewares ...Middleware) *Builder {
+	b.task.middlewares = append(b.task.middlewares, middlewares...)
+	return b
+}
+
+// FinalizedBy declares other tasks that run immediately after this one, even if it fails.
+// A finalizer shared by several tasks runs once, after the last of them.
+func (b *Builder) FinalizedBy(names ...string) *Builder {
+	b.task.finalizedBy = append(b.task.finalizedBy, names...)
+	return b
+}
+
+// Matrix declares that the task runs once for each of the values of the argument, which is
+// declared as an optional argument if it has not been already. Declaring several axes runs the
+// task once for every combination of their values. Matrix panics if no values are given, since
+// the task would never run.
+func (b *Builder) Matrix(arg string, values ...string) *Builder {
+	if len(values) == 0 {
+		panic(fmt.Sprintf("matrix axis %q of task %q must have at least one value", arg, b.task.Name()))
+	}
+	if !declaresArg(b.task, arg) {
+		b.OptionalArg(arg)
+	}
+
+	b.task.matrix = append(b.task.matrix, MatrixAxis{Arg: arg, Values: values})
+	return b
+}
+
+// MustRunAfter declares that the task runs after the given tasks when they are also part of
+// the run, without requiring them to run.
+func (b *Builder) MustRunAfter(names ...string) *Builder {
+	b.task.mustRunAfter = append(b.task.mustRunAfter, names...)
+	return b
+}
+
+// Requires declares shared resources the task holds while it runs. No more tasks than the
+// resource's capacity hold it at the same time.
+func (b *Builder) Requires(resources ...string) *Builder {
+	b.task.resources = append(b.task.resources, resources...)
+	return b
+}
+
+// ShouldRunAfter is like MustRunAfter, except the constraint is ignored when it would cause a cycle.
+func (b *Builder) ShouldRunAfter(names ...string) *Builder {
+	b.task.shouldRunAfter = append(b.task.shouldRunAfter, names...)
+	return b
 }
 
 // Hide the task from the task list.
@@ -87,6 +197,13 @@ func (b *Builder) Hide() *Builder {
 	return b
 }
 
+// Quiet hides the output of the task unless it fails, which suits noisy tasks whose output is
+// rarely of interest.
+func (b *Builder) Quiet() *Builder {
+	b.task.quiet = true
+	return b
+}
+
 // Defer declares other tasks that should run after this one.
 func (b *Builder) Defer(names ...string) *Builder {
 	b.task.deferredTasks = names
@@ -94,16 +211,33 @@ func (b *Builder) Defer(names ...string) *Builder {
 }
 
 type declaredTask struct {
+	aliases         []string
 	declaredArgs    []DeclaredTaskArg
 	dependencies    []string
 	name            string
 	description     string
+	longDescription string
 	executor        Executor
+	middlewares     []Middleware
 	continueOnError bool
+	deprecation     *Deprecation
 	hidden          bool
+	quiet           bool
 	deferredTasks   []string
+	finalizedBy     []string
+	matrix          []MatrixAxis
+	mustRunAfter    []string
+	shouldRunAfter  []string
+	autoNamespace   bool
+	aggregate       bool
+	completed       bool
+	param           string
+	resources       []string
 }
 
+func (t *declaredTask) Aliases() []string {
+	return t.aliases
+}
 func (t *declaredTask) ContinueOnError() bool {
 	return t.continueOnError
 }
@@ -113,18 +247,45 @@ func (t *declaredTask) DeclaredArgs() []DeclaredTaskArg {
 func (t *declaredTask) Dependencies() []string {
 	return t.dependencies
 }
+func (t *declaredTask) Deprecation() *Deprecation {
+	return t.deprecation
+}
 func (t *declaredTask) Description() string {
 	return t.description
 }
+func (t *declaredTask) FinalizedBy() []string {
+	return t.finalizedBy
+}
 func (t *declaredTask) Hidden() bool {
 	return t.hidden
 }
+func (t *declaredTask) LongDescription() string {
+	return t.longDescription
+}
 func (t *declaredTask) Executor() Executor {
-	return t.executor
+	return wrapExecutor(t, t.executor, t.middlewares)
+}
+func (t *declaredTask) Matrix() []MatrixAxis {
+	return t.matrix
+}
+func (t *declaredTask) MustRunAfter() []string {
+	return t.mustRunAfter
+}
+func (t *declaredTask) Param() string {
+	return t.param
 }
 func (t *declaredTask) Name() string {
 	return t.name
 }
+func (t *declaredTask) Quiet() bool {
+	return t.quiet
+}
+func (t *declaredTask) Resources() []string {
+	return t.resources
+}
 func (t *declaredTask) DeferredTasks() []string {
 	return t.deferredTasks
 }
+func (t *declaredTask) ShouldRunAfter() []string {
+	return t.shouldRunAfter
+}
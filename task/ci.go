@@ -0,0 +1,222 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+// ciProvider is a CI service whose logs understand markup for grouping output and annotating errors.
+type ciProvider string
+
+// The supported CI providers.
+const (
+	ciNone   ciProvider = ""
+	ciGitHub ciProvider = "github"
+	ciGitLab ciProvider = "gitlab"
+)
+
+// gitlabRunSection is the section holding the whole run, so that GitLab times the run as well as each task.
+const gitlabRunSection = "goke"
+
+// detectCI decides which CI markup to emit. An explicit -ci argument naming a provider wins, and
+// otherwise the provider is detected from the environment unless -ci=false.
+func detectCI(ciArg string, hasCIArg bool) (ciProvider, error) {
+	switch {
+	case hasCIArg && ciArg == "false":
+		return ciNone, nil
+	case hasCIArg && ciArg != trueString:
+		provider := ciProvider(ciArg)
+		if provider != ciGitHub && provider != ciGitLab {
+			return ciNone, fmt.Errorf("invalid ci %q, expected %q or %q", ciArg, ciGitHub, ciGitLab)
+		}
+		return provider, nil
+	case os.Getenv("GITHUB_ACTIONS") == trueString:
+		return ciGitHub, nil
+	case os.Getenv("GITLAB_CI") == trueString:
+		return ciGitLab, nil
+	default:
+		return ciNone, nil
+	}
+}
+
+// ciReporter wraps the output of each task in a collapsible group and annotates failed tasks
+// using the markup of the CI provider. On GitHub Actions, a summary of the run is also appended
+// to the step summary when summaryPath is set.
+type ciReporter struct {
+	reporter
+
+	provider    ciProvider
+	w           io.Writer
+	secrets     *internal.Masker
+	summaryPath string
+
+	// group is the task whose group is open. The cells of a matrix task are reported within the
+	// task's group, since GitHub doesn't nest groups.
+	group string
+}
+
+func (r *ciReporter) runStarted(tasks []Task) {
+	if r.provider == ciGitLab {
+		r.sectionStart(gitlabRunSection, "goke", false)
+	}
+	r.reporter.runStarted(tasks)
+}
+
+func (r *ciReporter) taskStarted(t Task) {
+	if r.group == "" {
+		r.group = t.Name()
+		r.groupStart(t.Name())
+	}
+	r.reporter.taskStarted(t)
+}
+
+func (r *ciReporter) taskFinished(t Task, result *TaskResult) {
+	r.reporter.taskFinished(t, result)
+	if r.group == t.Name() {
+		r.group = ""
+		r.groupEnd(t.Name())
+	}
+	if result.Err != nil {
+		r.annotate("error", fmt.Sprintf("%s failed", t.Name()), result.Err.Error())
+	}
+}
+
+func (r *ciReporter) deferredStarted() {
+	r.groupStart("run deferred tasks")
+	r.reporter.deferredStarted()
+}
+
+func (r *ciReporter) deferredFinished(t Task, result *TaskResult) {
+	r.reporter.deferredFinished(t, result)
+	if result.Err != nil {
+		r.annotate("warning", fmt.Sprintf("deferred %s failed", t.Name()), result.Err.Error())
+	}
+}
+
+func (r *ciReporter) deferredCompleted(elapsed time.Duration) {
+	r.reporter.deferredCompleted(elapsed)
+	r.groupEnd("run deferred tasks")
+}
+
+func (r *ciReporter) durationRegressed(regression *durationRegression) {
+	r.reporter.durationRegressed(regression)
+	r.annotate("warning", fmt.Sprintf("%s is slower", regression.result.Task), fmt.Sprintf(
+		"took %s, the median of the last %d runs is %s",
+		regression.result.Duration.Round(time.Millisecond),
+		regression.runs,
+		regression.median.Round(time.Millisecond)))
+}
+
+func (r *ciReporter) runCompleted(elapsed time.Duration, results []*TaskResult, failedTasks []string) {
+	r.reporter.runCompleted(elapsed, results, failedTasks)
+
+	switch r.provider {
+	case ciGitHub:
+		r.annotate("notice", "goke", fmt.Sprintf("ran %d task(s) in %s", len(results), elapsed.Round(time.Millisecond)))
+		if r.summaryPath != "" {
+			if err := r.writeStepSummary(elapsed, results, failedTasks); err != nil {
+				fmt.Fprintln(os.Stderr, "failed writing the step summary:", err)
+			}
+		}
+	case ciGitLab:
+		r.sectionEnd(gitlabRunSection)
+	}
+}
+
+func (r *ciReporter) groupStart(name string) {
+	switch r.provider {
+	case ciGitHub:
+		fmt.Fprintf(r.w, "::group::%s\n", name)
+	case ciGitLab:
+		r.sectionStart(gitlabSectionName(name), name, true)
+	}
+}
+
+func (r *ciReporter) groupEnd(name string) {
+	switch r.provider {
+	case ciGitHub:
+		fmt.Fprintln(r.w, "::endgroup::")
+	case ciGitLab:
+		r.sectionEnd(gitlabSectionName(name))
+	}
+}
+
+// annotate attaches the msg to the run as an error, warning or notice. GitLab has no annotations,
+// since its logs already show the human output of failures.
+func (r *ciReporter) annotate(level, title, msg string) {
+	if r.provider != ciGitHub {
+		return
+	}
+	fmt.Fprintf(r.w, "::%s title=%s::%s\n", level, escapeGitHubProperty(title), escapeGitHubData(r.secrets.Mask(msg)))
+}
+
+func (r *ciReporter) sectionStart(section, header string, collapsed bool) {
+	options := ""
+	if collapsed {
+		options = "[collapsed=true]"
+	}
+	fmt.Fprintf(r.w, "\x1b[0Ksection_start:%d:%s%s\r\x1b[0K%s\n", time.Now().Unix(), section, options, header)
+}
+
+func (r *ciReporter) sectionEnd(section string) {
+	fmt.Fprintf(r.w, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), section)
+}
+
+// writeStepSummary appends a markdown table of the results to the GitHub step summary.
+func (r *ciReporter) writeStepSummary(elapsed time.Duration, results []*TaskResult, failedTasks []string) error {
+	f, err := os.OpenFile(r.summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("### goke\n\n| Task | Status | Duration | Error |\n| --- | --- | --- | --- |\n")
+	for _, result := range results {
+		duration, errMsg := "", ""
+		if result.Duration > 0 {
+			duration = result.Duration.Round(time.Millisecond).String()
+		}
+		if result.Err != nil {
+			errMsg = firstLine(r.secrets.Mask(result.Err.Error()))
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n",
+			escapeMarkdownCell(result.Task), result.Status, duration, escapeMarkdownCell(errMsg))
+	}
+	if len(failedTasks) > 0 {
+		fmt.Fprintf(&sb, "\nFailed %s in %s\n", escapeMarkdownCell(strings.Join(failedTasks, ", ")), elapsed.Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(&sb, "\nCompleted in %s\n", elapsed.Round(time.Millisecond))
+	}
+
+	if _, err := io.WriteString(f, sb.String()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var gitlabSectionInvalid = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// gitlabSectionName replaces the characters GitLab doesn't allow in section names, such as those
+// of matrix cells.
+func gitlabSectionName(name string) string {
+	return "goke_" + gitlabSectionInvalid.ReplaceAllString(name, "_")
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,167 @@
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+func TestDetectCI(t *testing.T) {
+	for _, name := range []string{"GITHUB_ACTIONS", "GITLAB_CI"} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+
+	testCases := []struct {
+		env      string
+		ciArg    string
+		hasCIArg bool
+		expected ciProvider
+	}{
+		{"", "", false, ciNone},
+		{"GITHUB_ACTIONS", "", false, ciGitHub},
+		{"GITLAB_CI", "", false, ciGitLab},
+		{"GITHUB_ACTIONS", "false", true, ciNone},
+		{"GITHUB_ACTIONS", "true", true, ciGitHub},
+		{"", "gitlab", true, ciGitLab},
+	}
+
+	for _, tc := range testCases {
+		if tc.env != "" {
+			os.Setenv(tc.env, "true")
+		}
+		provider, err := detectCI(tc.ciArg, tc.hasCIArg)
+		if tc.env != "" {
+			os.Unsetenv(tc.env)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if provider != tc.expected {
+			t.Errorf("%s -ci=%s: expected %q but got %q", tc.env, tc.ciArg, tc.expected, provider)
+		}
+	}
+
+	if _, err := detectCI("jenkins", true); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func TestStepSummaryOption(t *testing.T) {
+	for _, name := range []string{"GITHUB_ACTIONS", "GITHUB_STEP_SUMMARY"} {
+		defer os.Setenv(name, os.Getenv(name))
+	}
+	os.Setenv("GITHUB_ACTIONS", "true")
+	os.Setenv("GITHUB_STEP_SUMMARY", "summary.md")
+
+	testCases := []struct {
+		arguments []string
+		expected  string
+	}{
+		{nil, "summary.md"},
+		{[]string{"-ci-summary=false"}, ""},
+		{[]string{"-ci=gitlab"}, ""},
+	}
+
+	for _, tc := range testCases {
+		opts, err := parseArgs(tc.arguments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.stepSummary != tc.expected {
+			t.Errorf("%v: expected the step summary %q but got %q", tc.arguments, tc.expected, opts.stepSummary)
+		}
+	}
+}
+
+func TestCIReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reg := NewRegistry()
+	declare(reg, "compile", false)
+	declare(reg, "test", true)
+	compile, _ := reg.findTask("compile")
+	test, _ := reg.findTask("test")
+
+	run := func(provider ciProvider, summaryPath string) string {
+		var out bytes.Buffer
+		rep := &ciReporter{
+			reporter:    &humanReporter{w: internal.NewPrefixWriter(&out)},
+			provider:    provider,
+			w:           &out,
+			secrets:     internal.NewMasker("hunter2"),
+			summaryPath: summaryPath,
+		}
+
+		rep.runStarted([]Task{compile, test})
+		var results []*TaskResult
+		for _, tc := range []struct {
+			task Task
+			err  error
+		}{{compile, nil}, {test, fmt.Errorf("2 tests failed\nwith password hunter2")}} {
+			rep.taskStarted(tc.task)
+			_, _ = fmt.Fprintln(rep.writer(), "output")
+			result := newTaskResult(tc.task.Name(), tc.err, time.Second)
+			rep.taskFinished(tc.task, result)
+			results = append(results, result)
+		}
+		rep.runCompleted(2*time.Second, results, []string{"test"})
+		return out.String()
+	}
+
+	summaryPath := filepath.Join(dir, "summary.md")
+	log := run(ciGitHub, summaryPath)
+	for _, expected := range []string{
+		"::group::compile\nSTART  | compile\n",
+		"FINISH | compile in 1s\n::endgroup::\n",
+		"::error title=test failed::2 tests failed%0Awith password ****\n",
+		"::notice title=goke::ran 2 task(s) in 2s\n",
+	} {
+		if !strings.Contains(log, expected) {
+			t.Errorf("expected the github log to contain %q but got:\n%s", expected, log)
+		}
+	}
+
+	summary, err := ioutil.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"| compile | ok | 1s |  |\n", "| test | failed | 1s | 2 tests failed |\n", "Failed test in 2s\n"} {
+		if !strings.Contains(string(summary), expected) {
+			t.Errorf("expected the step summary to contain %q but got:\n%s", expected, summary)
+		}
+	}
+
+	log = run(ciGitLab, "")
+	for _, expected := range []string{"section_start:", ":goke_compile[collapsed=true]\r\x1b[0Kcompile\n", ":goke_test\r\x1b[0K\n", ":goke\r\x1b[0K\n"} {
+		if !strings.Contains(log, expected) {
+			t.Errorf("expected the gitlab log to contain %q but got:\n%q", expected, log)
+		}
+	}
+	if strings.Contains(log, "::") {
+		t.Errorf("expected no github markup in the gitlab log but got:\n%q", log)
+	}
+
+	// the cells of a matrix task are reported within the task's group
+	var out bytes.Buffer
+	rep := &ciReporter{reporter: &humanReporter{w: internal.NewPrefixWriter(&out)}, provider: ciGitHub, w: &out}
+	cell := &matrixCell{Task: compile, name: "compile[goos=linux]"}
+	rep.taskStarted(compile)
+	rep.taskStarted(cell)
+	rep.taskFinished(cell, newTaskResult(cell.Name(), nil, time.Second))
+	rep.taskFinished(compile, newTaskResult(compile.Name(), nil, time.Second))
+	if strings.Count(out.String(), "::group::") != 1 || !strings.HasSuffix(out.String(), "FINISH | compile in 1s\n::endgroup::\n") {
+		t.Errorf("expected a single group for the matrix task but got:\n%s", out.String())
+	}
+}
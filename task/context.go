@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
+
+	"github.com/craiggwilson/goke/task/internal"
 )
 
 // NewContext makes a new Context.
@@ -28,6 +31,32 @@ func WithVerbose(verbose bool) ContextParam {
 	}
 }
 
+func withFinalizedResult(result *TaskResult) ContextParam {
+	return func(ctx *Context) {
+		ctx.finalized = result
+	}
+}
+
+func withOutputs(taskName string, outputs *outputStore, resolve outputResolver) ContextParam {
+	return func(ctx *Context) {
+		ctx.taskName = taskName
+		ctx.outputs = outputs
+		ctx.resolveOutputs = resolve
+	}
+}
+
+func withParam(param string) ContextParam {
+	return func(ctx *Context) {
+		ctx.param = param
+	}
+}
+
+func withSecrets(secrets *internal.Masker) ContextParam {
+	return func(ctx *Context) {
+		ctx.secrets = secrets
+	}
+}
+
 func WithUI(ui *TUI) ContextParam {
 	return func(ctx *Context) {
 		ctx.UI = ui
@@ -41,8 +70,17 @@ type Context struct {
 	UI      *TUI
 	Verbose bool
 
-	taskArgs map[string]string
-	w        io.Writer
+	finalized      *TaskResult
+	outputs        *outputStore
+	param          string
+	resolveOutputs outputResolver
+	secrets        *internal.Masker
+	taskArgs       map[string]string
+	taskName       string
+	w              io.Writer
+
+	mu      sync.Mutex
+	partial []byte
 }
 
 // Get returns an argument of the given name. If one doesn't exist,
@@ -57,26 +95,117 @@ func (ctx *Context) Get(name string) string {
 	return os.Getenv(name)
 }
 
+// FinalizedResult returns the result of the task being finalized when running
+// as a finalizer, or nil otherwise.
+func (ctx *Context) FinalizedResult() *TaskResult {
+	return ctx.finalized
+}
+
+// Param returns the parameter the task was generated for by a rule or by DeclareEach.
+func (ctx *Context) Param() string {
+	return ctx.param
+}
+
+// SetOutput publishes a value under the given key for dependent tasks to read with Output.
+func (ctx *Context) SetOutput(key string, value interface{}) {
+	if ctx.outputs == nil {
+		ctx.outputs = newOutputStore()
+	}
+	ctx.outputs.set(ctx.taskName, key, value)
+}
+
+// Output returns the value published under the given key by the named task. The current task
+// must depend on the named task, directly or transitively.
+func (ctx *Context) Output(taskName string, key string) (interface{}, error) {
+	if ctx.outputs == nil || ctx.resolveOutputs == nil {
+		return nil, fmt.Errorf("no outputs are available to read")
+	}
+
+	producer, err := ctx.resolveOutputs(taskName)
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := ctx.outputs.get(producer, key)
+	if !ok {
+		return nil, fmt.Errorf("task '%s' has no output %q", producer, key)
+	}
+	return v, nil
+}
+
+// OutputString returns the string value published under the given key by the named task.
+func (ctx *Context) OutputString(taskName string, key string) (string, error) {
+	v, err := ctx.Output(taskName, key)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("output %q of task '%s' is a %T, not a string", key, taskName, v)
+	}
+	return s, nil
+}
+
+// RegisterSecret masks the value in all output from now on, such as a token obtained while running.
+func (ctx *Context) RegisterSecret(value string) {
+	if ctx.secrets == nil {
+		ctx.secrets = internal.NewMasker()
+	}
+	ctx.secrets.Add(value)
+}
+
 // Log formats using the default formats for its operands sends it to the log.
 // Spaces are added between operands when neither is a string.
 func (ctx *Context) Log(v ...interface{}) {
-	_, _ = fmt.Fprint(ctx.w, v...)
+	_, _ = fmt.Fprint(ctx, v...)
 }
 
 // Logln formats using the default formats for its operands and sends it to the log.
 // Spaces are always added between operands and a newline is appended.
 func (ctx *Context) Logln(v ...interface{}) {
-	_, _ = fmt.Fprintln(ctx.w, v...)
+	_, _ = fmt.Fprintln(ctx, v...)
 }
 
 // Logf formats according to a format specifier and sends it to the log.
 func (ctx *Context) Logf(format string, v ...interface{}) {
-	_, _ = fmt.Fprintf(ctx.w, format, v...)
+	_, _ = fmt.Fprintf(ctx, format, v...)
 }
 
-// Writer implements the io.Writer interface.
+// Write implements the io.Writer interface, masking registered secrets. Since a secret may be split
+// across writes, the output at the end which may be the beginning of a secret is held until the
+// next write or the task finishes.
 func (ctx *Context) Write(p []byte) (n int, err error) {
-	return ctx.w.Write(p)
+	if ctx.secrets == nil {
+		return ctx.w.Write(p)
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	data := append(ctx.partial, p...)
+	held := ctx.secrets.Pending(data)
+	ctx.partial = append([]byte(nil), data[len(data)-held:]...)
+	if held == len(data) {
+		return len(p), nil
+	}
+	if _, err := ctx.w.Write(ctx.secrets.MaskBytes(data[:len(data)-held])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush writes the output held by Write.
+func (ctx *Context) flush() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	if len(ctx.partial) == 0 {
+		return nil
+	}
+	_, err := ctx.w.Write(ctx.secrets.MaskBytes(ctx.partial))
+	ctx.partial = nil
+	return err
 }
 
 // CopyArgs returns a copy of the current context's task arguments.
@@ -0,0 +1,66 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// criticalPath returns the chain of dependencies among the planned tasks with the longest total
+// duration, which bounds how fast the plan can run however many of its tasks run at the same time.
+func criticalPath(tasksToRun []Task, durations map[string]time.Duration) ([]Task, time.Duration) {
+	lookup := taskLookup(tasksToRun)
+	total := make(map[string]time.Duration, len(tasksToRun))
+	previous := make(map[string]Task, len(tasksToRun))
+
+	// the tasks are sorted, so dependencies are always visited before their dependents
+	var last Task
+	for _, t := range tasksToRun {
+		var longest time.Duration
+		for _, name := range t.Dependencies() {
+			dep, ok := lookup[strings.ToLower(name)]
+			if !ok {
+				continue
+			}
+			if d := total[dep.Name()]; previous[t.Name()] == nil || d > longest {
+				longest = d
+				previous[t.Name()] = dep
+			}
+		}
+		total[t.Name()] = longest + durations[t.Name()]
+
+		// prefer dependents, which end the chain with the task that was asked for
+		if last == nil || total[t.Name()] >= total[last.Name()] {
+			last = t
+		}
+	}
+	if last == nil {
+		return nil, 0
+	}
+
+	var path []Task
+	for t := last; t != nil; t = previous[t.Name()] {
+		path = append([]Task{t}, path...)
+	}
+	return path, total[last.Name()]
+}
+
+// printCriticalPath prints the tasks of the critical path along with their durations.
+func printCriticalPath(ui *TUI, out io.Writer, path []Task, durations map[string]time.Duration, total, elapsed time.Duration) {
+	fmt.Fprintf(out, "%s: %s of %s\n", ui.Highlight("CRITICAL PATH"), ui.Duration(total.Round(time.Microsecond).String()), elapsed.Round(time.Microsecond))
+
+	nameWidth := 0
+	for _, t := range path {
+		if len(t.Name()) > nameWidth {
+			nameWidth = len(t.Name())
+		}
+	}
+	for _, t := range path {
+		line := "  " + ui.TaskName(pad(t.Name(), nameWidth))
+		if d := durations[t.Name()]; d > 0 {
+			line += "  " + ui.Duration(d.Round(time.Microsecond).String())
+		}
+		fmt.Fprintln(out, strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,44 @@
+package task
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCriticalPath(t *testing.T) {
+	registry := NewRegistry()
+	declare(registry, "a", false)
+	declare(registry, "b", false)
+	declare(registry, "c", false).DependsOn("A", "b")
+	declare(registry, "d", false)
+	declare(registry, "e", false).DependsOn("c")
+	registry.Declare("all").DependsOn("d", "e")
+
+	tasksToRun, err := sortTasksToRun(registry.Tasks(), []string{"all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	durations := map[string]time.Duration{
+		"a": time.Second,
+		"b": 2 * time.Second,
+		"c": 3 * time.Second,
+		"d": 5 * time.Second,
+		"e": time.Second,
+	}
+	path, total := criticalPath(tasksToRun, durations)
+
+	var buf bytes.Buffer
+	printCriticalPath(nil, &buf, path, durations, total, 12*time.Second)
+
+	expected := `CRITICAL PATH: 6s of 12s
+  b    2s
+  c    3s
+  e    1s
+  all
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
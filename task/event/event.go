@@ -0,0 +1,134 @@
+// Package event defines the events written by goke when run with -json. Each line of the stream
+// is one Event encoded as JSON.
+//
+// Every event has the schema version, its type, the id of the run, a sequence number which
+// increases by one with each event of the run, and the time it happened in RFC3339Nano. The
+// remaining fields depend on the type:
+//
+//	run_start            tasks: the planned tasks in order
+//	run_finish           status, durationMs, failedTasks
+//	task_start           task, deferred
+//	task_output          task, deferred, line, and data when the line is a JSON object
+//	task_finish          task, deferred, status, durationMs, error, stack
+//	task_skip            task, deferred, error
+//	task_deprecated      task, message, replacement
+//	deferred_start       (none)
+//	deferred_finish      durationMs
+//	unused_arg           arg
+//	duration_regression  task, durationMs, medianMs, runs
+//	critical_path        tasks, durationMs
+//	warning              message, error
+//
+// Fields which are empty, zero or false are omitted. New event types and fields may be added
+// without changing the schema version, so consumers should ignore what they don't know.
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SchemaVersion is the version of the event schema. It changes when fields are removed or change meaning.
+const SchemaVersion = 1
+
+// Type is the type of an event.
+type Type string
+
+// The types of events.
+const (
+	RunStart           Type = "run_start"
+	RunFinish          Type = "run_finish"
+	TaskStart          Type = "task_start"
+	TaskOutput         Type = "task_output"
+	TaskFinish         Type = "task_finish"
+	TaskSkip           Type = "task_skip"
+	TaskDeprecated     Type = "task_deprecated"
+	DeferredStart      Type = "deferred_start"
+	DeferredFinish     Type = "deferred_finish"
+	UnusedArg          Type = "unused_arg"
+	DurationRegression Type = "duration_regression"
+	CriticalPath       Type = "critical_path"
+	Warning            Type = "warning"
+)
+
+// The statuses of tasks and runs.
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Event is an event of a run.
+type Event struct {
+	Version int       `json:"version"`
+	Type    Type      `json:"event"`
+	RunID   string    `json:"runId"`
+	Seq     int64     `json:"seq"`
+	Time    time.Time `json:"time"`
+
+	// Task is the name of the task the event is about.
+	Task string `json:"task,omitempty"`
+	// Tasks are the planned tasks of a run_start, and the chain of tasks of a critical_path.
+	Tasks []string `json:"tasks,omitempty"`
+	// Deferred is whether the task is a deferred task.
+	Deferred bool `json:"deferred,omitempty"`
+	// Status is the outcome of a task or run.
+	Status string `json:"status,omitempty"`
+	// DurationMs is the duration in milliseconds.
+	DurationMs float64 `json:"durationMs,omitempty"`
+	// FailedTasks are the tasks which failed in a run.
+	FailedTasks []string `json:"failedTasks,omitempty"`
+	// Error is the error of a failed or skipped task, or of a warning.
+	Error string `json:"error,omitempty"`
+	// Stack is the stack trace of a task which panicked.
+	Stack string `json:"stack,omitempty"`
+	// Line is a line of task output, without the newline.
+	Line string `json:"line,omitempty"`
+	// Data is the line of task output decoded, when it is a JSON object.
+	Data json.RawMessage `json:"data,omitempty"`
+	// Arg is the name of an unused argument.
+	Arg string `json:"arg,omitempty"`
+	// Message describes a warning or a deprecation.
+	Message string `json:"message,omitempty"`
+	// Replacement is the task to use instead of a deprecated task.
+	Replacement string `json:"replacement,omitempty"`
+	// MedianMs is the median duration in milliseconds of a task in previous runs.
+	MedianMs float64 `json:"medianMs,omitempty"`
+	// Runs is the number of previous runs the median was taken from.
+	Runs int `json:"runs,omitempty"`
+}
+
+// Milliseconds converts a duration to the milliseconds used by the duration fields.
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Duration converts the milliseconds of a duration field to a time.Duration.
+func Duration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// NewDecoder makes a Decoder reading events from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{d: json.NewDecoder(r)}
+}
+
+// Decoder reads a stream of events.
+type Decoder struct {
+	d *json.Decoder
+}
+
+// Decode reads the next event. It returns io.EOF at the end of the stream, and an error for events
+// with a newer schema version than SchemaVersion.
+func (d *Decoder) Decode() (*Event, error) {
+	var e Event
+	if err := d.d.Decode(&e); err != nil {
+		return nil, err
+	}
+	if e.Version > SchemaVersion {
+		return nil, fmt.Errorf("event %d has schema version %d, but only versions up to %d are supported", e.Seq, e.Version, SchemaVersion)
+	}
+	return &e, nil
+}
@@ -0,0 +1,33 @@
+package event
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecoder(t *testing.T) {
+	stream := `{"version":1,"event":"task_finish","runId":"1","seq":1,"time":"2020-01-02T03:04:05Z","task":"test","status":"failed","durationMs":1500.5,"error":"exit status 1","unknownField":true}
+{"version":2,"event":"task_start","runId":"1","seq":2,"time":"2020-01-02T03:04:06Z","task":"lint"}
+`
+	d := NewDecoder(strings.NewReader(stream))
+
+	e, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != TaskFinish || e.Task != "test" || e.Status != StatusFailed || e.Error != "exit status 1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if Duration(e.DurationMs) != 1500500*time.Microsecond {
+		t.Errorf("expected a duration of 1.5005s but got %s", Duration(e.DurationMs))
+	}
+
+	if _, err := d.Decode(); err == nil {
+		t.Error("expected an error for a newer schema version")
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("expected io.EOF but got %v", err)
+	}
+}
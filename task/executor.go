@@ -1,4 +1,49 @@
 package task
 
+import (
+	"fmt"
+	"runtime/debug"
+)
+
 // Executor executes the body of a task.
 type Executor func(*Context) error
+
+// Middleware wraps the Executor of a task with additional behavior.
+type Middleware func(Task, Executor) Executor
+
+// wrapExecutor applies the middlewares around the executor such that the first middleware is the outermost.
+func wrapExecutor(t Task, executor Executor, middlewares []Middleware) Executor {
+	if executor == nil {
+		return nil
+	}
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		executor = middlewares[i](t, executor)
+	}
+	return executor
+}
+
+// PanicError is the error reported for a task whose executor panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// runExecutor runs the executor, converting a panic into a *PanicError.
+func runExecutor(executor Executor, ctx *Context) (err error) {
+	defer func() {
+		_ = ctx.flush()
+		if v := recover(); v != nil {
+			err = &PanicError{
+				Value: v,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+
+	return executor(ctx)
+}
@@ -0,0 +1,129 @@
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockGracePeriod is how long a lock file may be without a pid, as while its owner is writing
+// it, before it is considered to be left behind by a process which crashed.
+var lockGracePeriod = 10 * time.Second
+
+// tryCreateLockFile takes the lock by exclusively creating the file without blocking, and writes
+// the pid of this process to it. A lock file left behind by a process which has exited, such as
+// one which crashed, is stale and is taken over. The file is removed when the lock is released.
+func tryCreateLockFile(path string) (func(), bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err == nil {
+		_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+			return nil, false, err
+		}
+		return releaseLockFile(path), true, nil
+	}
+	if !os.IsExist(err) {
+		return nil, false, err
+	}
+
+	contents, stale := staleLockFile(path)
+	if !stale {
+		return nil, false, nil
+	}
+	if ok, err := takeOverLockFile(path, contents); err != nil || !ok {
+		return nil, false, err
+	}
+	return releaseLockFile(path), true, nil
+}
+
+// takeOverLockFile replaces the stale lock file, whose contents were found to be stale, with one
+// holding the pid of this process. Only one process may take over a lock file at a time, so the
+// others don't replace the lock file once it has been taken over. The new lock file is written
+// to a temporary file and renamed into place, so that the lock file always holds a pid.
+func takeOverLockFile(path string, contents string) (bool, error) {
+	takeover := path + ".takeover"
+	f, err := os.OpenFile(takeover, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		if !os.IsExist(err) {
+			return false, err
+		}
+		if info, err := os.Stat(takeover); err == nil && time.Since(info.ModTime()) > lockGracePeriod {
+			// left behind by a process which crashed while taking over the lock
+			_ = os.Remove(takeover)
+		}
+		return false, nil
+	}
+	_ = f.Close()
+	defer os.Remove(takeover)
+
+	// the lock file may have been released or taken over since it was found to be stale
+	if b, err := ioutil.ReadFile(path); err != nil || string(b) != contents {
+		return false, nil
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return false, err
+	}
+	_, err = fmt.Fprintf(tmp, "%d\n", os.Getpid())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return false, fmt.Errorf("failed taking over stale lock: %v", err)
+	}
+
+	pid, ok := lockFilePID(path)
+	return ok && pid == os.Getpid(), nil
+}
+
+// releaseLockFile returns a func removing the lock file when it is still held by this process.
+func releaseLockFile(path string) func() {
+	return func() {
+		if pid, ok := lockFilePID(path); ok && pid == os.Getpid() {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// staleLockFile indicates whether the lock file was left behind by a process which has exited,
+// returning its contents. A lock file without a pid is held for the grace period, since its owner
+// may not have written it yet.
+func staleLockFile(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return string(b), time.Since(info.ModTime()) > lockGracePeriod
+	}
+	return string(b), !processExists(pid)
+}
+
+// lockFilePID returns the pid written to the lock file.
+func lockFilePID(path string) (int, bool) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	return pid, err == nil
+}
@@ -0,0 +1,27 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package task
+
+import (
+	"os"
+)
+
+// tryLockFile takes the lock by exclusively creating the file without blocking. The file is
+// removed when the lock is released, and is replaced when its process has exited without
+// releasing it.
+func tryLockFile(path string) (func(), bool, error) {
+	return tryCreateLockFile(path)
+}
+
+// processExists indicates whether the process with the pid is running. On Windows, a process
+// which has exited can't be found. Elsewhere a process is always found, so a stale lock file
+// must be removed by hand.
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -0,0 +1,391 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+const (
+	defaultRunsDir = ".goke/runs"
+	runIDFormat    = "20060102-150405.000000"
+	lastRunID      = "last"
+
+	runOutputFile = "output.log"
+	runEventsFile = "events.jsonl"
+	runRecordFile = "run.json"
+
+	// a task has regressed when it takes regressionFactor times longer than the median of its
+	// durations in the last regressionWindow runs, provided it ran at least regressionMinRuns times
+	// and its median is at least regressionMinDuration.
+	regressionWindow      = 10
+	regressionFactor      = 2.0
+	regressionMinRuns     = 3
+	regressionMinDuration = 100 * time.Millisecond
+)
+
+// WithRunHistory records every run in .goke/runs, keeping the output, a JSON event log and the
+// results of the last keep runs. Previous runs can be listed with -history and replayed with
+// -history=<id>, and tasks which take much longer than they used to are reported. The recorded
+// durations are also used to run the tasks on the critical path of a plan first, and to estimate
+// the tasks which didn't run in the -critical-path report.
+func WithRunHistory(keep int) RegistryOption {
+	return func(r *Registry) {
+		r.history = nil
+		if keep > 0 {
+			r.history = &runHistory{dir: defaultRunsDir, keep: keep}
+		}
+	}
+}
+
+// runHistory is the directory holding the recorded runs.
+type runHistory struct {
+	dir  string
+	keep int
+}
+
+// runRecord describes a recorded run.
+type runRecord struct {
+	ID        string           `json:"id"`
+	Args      []string         `json:"args"`
+	Tasks     []string         `json:"tasks"`
+	StartTime time.Time        `json:"startTime"`
+	Duration  time.Duration    `json:"duration"`
+	Status    TaskStatus       `json:"status"`
+	Results   []recordedResult `json:"results"`
+}
+
+type recordedResult struct {
+	Task     string        `json:"task"`
+	Status   TaskStatus    `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// start creates the directory of a new run. Failing to record a run shouldn't fail the run, so
+// errors are printed and nil is returned.
+func (h *runHistory) start(opts *runOptions) *historyRun {
+	if h == nil {
+		return nil
+	}
+
+	startTime := time.Now()
+	run := &historyRun{
+		history: h,
+		secrets: opts.secrets,
+		record: runRecord{
+			ID:        startTime.Format(runIDFormat),
+			Args:      append([]string(nil), opts.arguments...),
+			Tasks:     opts.taskNames,
+			StartTime: startTime,
+		},
+	}
+	run.dir = filepath.Join(h.dir, run.record.ID)
+
+	err := os.MkdirAll(run.dir, 0777)
+	if err == nil {
+		run.output, err = os.Create(filepath.Join(run.dir, runOutputFile))
+	}
+	if err == nil {
+		run.events, err = os.Create(filepath.Join(run.dir, runEventsFile))
+	}
+	if err != nil {
+		run.close()
+		fmt.Fprintln(os.Stderr, "failed recording the run:", err)
+		return nil
+	}
+
+	return run
+}
+
+// records returns the recorded runs from oldest to newest.
+func (h *runHistory) records() ([]*runRecord, error) {
+	ids, err := h.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*runRecord
+	for _, id := range ids {
+		record, err := h.load(id)
+		if err != nil {
+			// still running or interrupted
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (h *runHistory) ids() ([]string, error) {
+	entries, err := ioutil.ReadDir(h.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (h *runHistory) load(id string) (*runRecord, error) {
+	data, err := ioutil.ReadFile(filepath.Join(h.dir, id, runRecordFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var record runRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// prune removes all but the newest runs.
+func (h *runHistory) prune() error {
+	ids, err := h.ids()
+	if err != nil {
+		return err
+	}
+
+	for len(ids) > h.keep {
+		if err := os.RemoveAll(filepath.Join(h.dir, ids[0])); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// durationRegression is a task which took much longer than it usually does.
+type durationRegression struct {
+	result *TaskResult
+	median time.Duration
+	runs   int
+}
+
+// recentDurations returns the durations of each task in its last regressionWindow successful runs.
+func (h *runHistory) recentDurations() map[string][]time.Duration {
+	if h == nil {
+		return nil
+	}
+
+	records, err := h.records()
+	if err != nil {
+		return nil
+	}
+
+	durations := make(map[string][]time.Duration)
+	for i := len(records) - 1; i >= 0; i-- {
+		for _, result := range records[i].Results {
+			if result.Status == StatusOK && len(durations[result.Task]) < regressionWindow {
+				durations[result.Task] = append(durations[result.Task], result.Duration)
+			}
+		}
+	}
+	return durations
+}
+
+// estimates returns the median duration of each task in its recent successful runs.
+func (h *runHistory) estimates() map[string]time.Duration {
+	durations := h.recentDurations()
+	if len(durations) == 0 {
+		return nil
+	}
+
+	estimates := make(map[string]time.Duration, len(durations))
+	for task, previous := range durations {
+		estimates[task] = medianDuration(previous)
+	}
+	return estimates
+}
+
+// regressions compares the durations of the successful results with their durations in previous runs.
+func (h *runHistory) regressions(results []*TaskResult) []*durationRegression {
+	durations := h.recentDurations()
+	if len(durations) == 0 {
+		return nil
+	}
+
+	var regressions []*durationRegression
+	for _, result := range results {
+		previous := durations[result.Task]
+		if result.Status != StatusOK || len(previous) < regressionMinRuns {
+			continue
+		}
+
+		median := medianDuration(previous)
+		if median >= regressionMinDuration && float64(result.Duration) >= regressionFactor*float64(median) {
+			regressions = append(regressions, &durationRegression{result: result, median: median, runs: len(previous)})
+		}
+	}
+	return regressions
+}
+
+func medianDuration(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// historyRun is a run being recorded.
+type historyRun struct {
+	history *runHistory
+	secrets *internal.Masker
+	dir     string
+	record  runRecord
+	output  *os.File
+	events  *os.File
+}
+
+// tee returns a writer which also records the output of the run.
+func (run *historyRun) tee(w io.Writer) io.Writer {
+	if run == nil {
+		return w
+	}
+	return io.MultiWriter(w, run.output)
+}
+
+// reporter returns a reporter which also records the events of the run.
+func (run *historyRun) reporter(rep reporter, opts *runOptions) reporter {
+	if run == nil {
+		return rep
+	}
+	return multiReporter{rep, &historyReporter{
+		jsonReporter: &jsonReporter{logger: newJSONLogger(run.events, opts), verbose: opts.verbose},
+		run:          run,
+	}}
+}
+
+// finish writes the record of the run and removes the runs which are no longer kept.
+func (run *historyRun) finish(elapsed time.Duration, results []*TaskResult, failedTasks []string) error {
+	defer run.close()
+
+	// the secrets are only all known once the run has completed
+	for i, arg := range run.record.Args {
+		run.record.Args[i] = run.secrets.Mask(arg)
+	}
+	run.record.Duration = elapsed
+	run.record.Status = StatusOK
+	if len(failedTasks) > 0 {
+		run.record.Status = StatusFailed
+	}
+	for _, result := range results {
+		recorded := recordedResult{Task: result.Task, Status: result.Status, Duration: result.Duration}
+		if result.Err != nil {
+			recorded.Error = run.secrets.Mask(result.Err.Error())
+		}
+		run.record.Results = append(run.record.Results, recorded)
+	}
+
+	data, err := json.MarshalIndent(run.record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(run.dir, runRecordFile), data, 0666); err != nil {
+		return err
+	}
+
+	return run.history.prune()
+}
+
+func (run *historyRun) close() {
+	if run.output != nil {
+		_ = run.output.Close()
+	}
+	if run.events != nil {
+		_ = run.events.Close()
+	}
+}
+
+// historyReporter writes the events of a run to its JSON event log and, once the run completes, its record.
+type historyReporter struct {
+	*jsonReporter
+	run *historyRun
+}
+
+func (r *historyReporter) runCompleted(elapsed time.Duration, results []*TaskResult, failedTasks []string) {
+	r.jsonReporter.runCompleted(elapsed, results, failedTasks)
+	if err := r.run.finish(elapsed, results, failedTasks); err != nil {
+		fmt.Fprintln(os.Stderr, "failed recording the run:", err)
+	}
+}
+
+// printHistory lists the recorded runs when id is "true", and otherwise replays the output of the identified run.
+func printHistory(ui *TUI, h *runHistory, id string, out io.Writer) error {
+	if h == nil {
+		return fmt.Errorf("run history is not enabled")
+	}
+
+	records, err := h.records()
+	if err != nil {
+		return err
+	}
+
+	if id != trueString {
+		if id == lastRunID && len(records) > 0 {
+			id = records[len(records)-1].ID
+		}
+		return replayRun(ui, h, id, out)
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(out, "no recorded runs")
+		return nil
+	}
+
+	idWidth, statusWidth := len("ID"), len("STATUS")
+	for _, record := range records {
+		if len(record.ID) > idWidth {
+			idWidth = len(record.ID)
+		}
+		if len(record.Status) > statusWidth {
+			statusWidth = len(record.Status)
+		}
+	}
+
+	fmt.Fprintln(out, ui.Highlight(fmt.Sprintf("%s  %s  %-10s  %s", pad("ID", idWidth), pad("STATUS", statusWidth), "DURATION", "TASKS")))
+	for _, record := range records {
+		fmt.Fprintf(out, "%s  %s  %s  %s\n",
+			pad(record.ID, idWidth),
+			colorStatus(ui, record.Status, pad(string(record.Status), statusWidth)),
+			ui.Duration(pad(record.Duration.Round(time.Millisecond).String(), 10)),
+			strings.Join(record.Tasks, " "))
+	}
+	return nil
+}
+
+// replayRun writes the recorded output of a run, dropping its colors when colors are disabled.
+func replayRun(ui *TUI, h *runHistory, id string, out io.Writer) error {
+	data, err := ioutil.ReadFile(filepath.Join(h.dir, filepath.Base(id), runOutputFile))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("unknown run '%s'", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if ui == nil {
+		data = internal.ANSIEscape.ReplaceAll(data, nil)
+	}
+	_, err = out.Write(data)
+	return err
+}
@@ -0,0 +1,114 @@
+package task
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	registry := NewRegistry(WithRunHistory(2))
+	registry.history.dir = dir
+	declare(registry, "compile", false)
+	declare(registry, "test", true)
+
+	for _, name := range []string{"compile", "test", "compile"} {
+		_ = Run(registry, []string{name, "-color=false", "-progress=false"})
+		// run ids have microsecond precision
+		time.Sleep(time.Millisecond)
+	}
+
+	records, err := registry.history.records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 runs to be kept but got %d", len(records))
+	}
+	if records[0].Status != StatusFailed || records[0].Results[0].Error != "error in test" {
+		t.Errorf("expected the failed test run to be recorded but got %+v", records[0])
+	}
+	if records[1].Status != StatusOK || strings.Join(records[1].Tasks, ",") != "compile" {
+		t.Errorf("expected the compile run to be recorded but got %+v", records[1])
+	}
+
+	events, err := ioutil.ReadFile(filepath.Join(dir, records[1].ID, runEventsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(events), `"event":"task_start"`) || !strings.Contains(string(events), `"task":"compile"`) {
+		t.Errorf("expected the events to be recorded but got:\n%s", events)
+	}
+
+	var buf bytes.Buffer
+	if err := printHistory(nil, registry.history, trueString, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], records[0].ID+"  failed") || !strings.HasSuffix(lines[2], "compile") {
+		t.Errorf("unexpected history:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := printHistory(nil, registry.history, lastRunID, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "START  | compile\nFINISH | compile in ") {
+		t.Errorf("expected the last run to be replayed but got:\n%s", buf.String())
+	}
+
+	if err := printHistory(nil, registry.history, "bogus", &buf); err == nil {
+		t.Error("expected an error for an unknown run")
+	}
+}
+
+func TestRunHistoryRegressions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h := &runHistory{dir: dir, keep: 20}
+	for i, d := range []time.Duration{time.Second, 3 * time.Second, time.Second, 2 * time.Second} {
+		id := "run" + string(rune('a'+i))
+		run := &historyRun{history: h, dir: filepath.Join(dir, id), record: runRecord{ID: id}}
+		if err := os.MkdirAll(run.dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+		results := []*TaskResult{
+			{Task: "test", Status: StatusOK, Duration: d},
+			{Task: "lint", Status: StatusOK, Duration: 10 * time.Millisecond},
+		}
+		if err := run.finish(d, results, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	regressions := h.regressions([]*TaskResult{
+		{Task: "test", Status: StatusOK, Duration: 3 * time.Second},
+		{Task: "lint", Status: StatusOK, Duration: time.Second},
+		{Task: "new", Status: StatusOK, Duration: time.Hour},
+	})
+	if len(regressions) != 1 {
+		t.Fatalf("expected 1 regression but got %d", len(regressions))
+	}
+	if regressions[0].result.Task != "test" || regressions[0].median != 1500*time.Millisecond || regressions[0].runs != 4 {
+		t.Errorf("unexpected regression %+v", regressions[0])
+	}
+
+	estimates := h.estimates()
+	if len(estimates) != 2 || estimates["test"] != 1500*time.Millisecond || estimates["lint"] != 10*time.Millisecond {
+		t.Errorf("unexpected estimates %v", estimates)
+	}
+}
@@ -0,0 +1,6 @@
+package internal
+
+import "regexp"
+
+// ANSIEscape matches the escape sequences which color and style terminal output.
+var ANSIEscape = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
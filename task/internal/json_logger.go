@@ -1,76 +1,161 @@
 package internal
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
-)
 
-const (
-	startTimeNanosField = "startTimeNanos"
-	msgField            = "msg"
+	"github.com/craiggwilson/goke/task/event"
 )
 
+// JSONLogger writes the events of a run as JSON lines, numbering them and stamping them
+// with the id of the run.
 type JSONLogger struct {
-	w  io.Writer
-	id string
+	w         io.Writer
+	runID     string
+	masker    *Masker
+	stripANSI bool
+
+	mu       sync.Mutex
+	seq      int64
+	task     string
+	deferred bool
 }
 
-// NewJSONLogger creates a new JSONLogger which generates valid JSON ouputs and includes the same unique
-// id in each log line generated through this logger.
-func NewJSONLogger(w io.Writer) *JSONLogger {
+// NewJSONLogger creates a new JSONLogger which writes the events of the identified run to w.
+func NewJSONLogger(w io.Writer, runID string) *JSONLogger {
 	return &JSONLogger{
-		w: w,
-		// Use nano timestamps as a decent unique identifier that does not
-		// require any external dependencies.
-		id: fmt.Sprintf("%d", time.Now().UnixNano()),
+		w:     w,
+		runID: runID,
 	}
 }
 
-func (j *JSONLogger) Logln(msg string, fields map[string]string) {
-	_, _ = j.logln(msg, fields)
+// SetMasker sets the masker used to hide secrets in the events.
+func (j *JSONLogger) SetMasker(masker *Masker) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.masker = masker
+}
+
+// SetStripANSI sets whether ANSI escape sequences, such as colors, are removed from task output.
+func (j *JSONLogger) SetStripANSI(strip bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stripANSI = strip
 }
 
-func (j *JSONLogger) logln(msg string, fields map[string]string) (int, error) {
-	fields[msgField] = msg
-	fields[startTimeNanosField] = j.id
-	logString, err := json.Marshal(fields)
+// Log writes the event, filling in its version, run id, sequence number and, unless set, time.
+func (j *JSONLogger) Log(e *event.Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, _ = j.log(e)
+}
+
+func (j *JSONLogger) log(e *event.Event) (int, error) {
+	j.seq++
+	e.Version = event.SchemaVersion
+	e.RunID = j.runID
+	e.Seq = j.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+
+	// only the text of the event is masked, so that names, types and numbers are left intact
+	e.Error = j.masker.Mask(e.Error)
+	e.Stack = j.masker.Mask(e.Stack)
+	e.Line = j.masker.Mask(e.Line)
+	e.Message = j.masker.Mask(e.Message)
+	e.Data = j.maskData(e.Data)
+
+	data, err := json.Marshal(e)
 	if err != nil {
-		panic(err)
+		return 0, err
 	}
-	return fmt.Fprintln(j.w, string(logString))
+	return fmt.Fprintln(j.w, string(data))
 }
 
-// Write implements the [io.Writer] interface. The input is expected to be log lines separated by newlines.
-// If the log line is already valid JSON, the logger simply adds the id field. If the log line is not valid JSON,
-// then the log line is wrapped in a JSON.
-func (j *JSONLogger) Write(p []byte) (int, error) {
-	// p may contain any number of log lines, so split the input by newline.
-	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
-		_, err := j.writeLine([]byte(line))
-		if err != nil {
-			return 0, err
+// maskData masks the string values in the data, leaving its keys and numbers intact.
+func (j *JSONLogger) maskData(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 || j.masker.Mask(string(data)) == string(data) {
+		return data
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil
+	}
+	masked, err := json.Marshal(j.maskValue(v))
+	if err != nil {
+		return nil
+	}
+	return masked
+}
+
+func (j *JSONLogger) maskValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return j.masker.Mask(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = j.maskValue(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = j.maskValue(v[k])
 		}
 	}
-	return len(p), nil
+	return v
 }
 
-func (j *JSONLogger) writeLine(line []byte) (int, error) {
-	log := map[string]interface{}{}
+// SetTask sets the task which output written to the logger belongs to.
+func (j *JSONLogger) SetTask(task string, deferred bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
 
-	err := json.Unmarshal(line, &log)
-	if err != nil {
-		// If the log is not a valid JSON, log it as a field of a valid JSON.
-		return j.logln(string(line), map[string]string{})
-	}
+	j.task = task
+	j.deferred = deferred
+}
 
-	// If the log is already in JSON format, just add in the id field.
-	log[startTimeNanosField] = j.id
-	logString, err := json.Marshal(log)
-	if err != nil {
-		return 0, err
+// Write implements the [io.Writer] interface. The input is expected to be lines of task output separated by
+// newlines, each of which is logged as a task_output event. Lines which are JSON objects are also included
+// as the event's data.
+func (j *JSONLogger) Write(p []byte) (int, error) {
+	return j.WriteTimed(p, time.Now())
+}
+
+// WriteTimed implements the TimedWriter interface, using t as the time of each line.
+func (j *JSONLogger) WriteTimed(p []byte, t time.Time) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// p may contain any number of lines, so split the input by newline.
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if j.stripANSI {
+			line = ANSIEscape.ReplaceAllString(line, "")
+		}
+		e := &event.Event{
+			Type:     event.TaskOutput,
+			Time:     t,
+			Task:     j.task,
+			Deferred: j.deferred,
+			Line:     line,
+		}
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+			e.Data = json.RawMessage(trimmed)
+		}
+		if _, err := j.log(e); err != nil {
+			return 0, err
+		}
 	}
-	return fmt.Fprintln(j.w, string(logString))
+	return len(p), nil
 }
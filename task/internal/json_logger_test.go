@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/craiggwilson/goke/task/event"
+)
+
+func TestJSONLogger(t *testing.T) {
+	lineTime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.FixedZone("EST", -5*60*60))
+
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf, "42")
+	logger.Log(&event.Event{Type: event.TaskStart, Task: "compile"})
+	logger.SetTask("compile", false)
+	_, _ = logger.WriteTimed([]byte("plain\n{\"level\":\"info\"}\n"), lineTime)
+
+	var events []*event.Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e event.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Version != event.SchemaVersion || e.RunID != "42" || e.Seq != int64(len(events)+1) || e.Time.Location() != time.UTC {
+			t.Errorf("unexpected envelope %+v", e)
+		}
+		events = append(events, &e)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events but got %d:\n%s", len(events), buf.String())
+	}
+	if events[1].Type != event.TaskOutput || events[1].Task != "compile" || events[1].Line != "plain" || events[1].Data != nil {
+		t.Errorf("unexpected output event %+v", events[1])
+	}
+	if !events[1].Time.Equal(lineTime) {
+		t.Errorf("expected the time %v but got %v", lineTime, events[1].Time)
+	}
+	if !reflect.DeepEqual(events[2].Data, json.RawMessage(`{"level":"info"}`)) {
+		t.Errorf("expected the JSON line as data but got %s", events[2].Data)
+	}
+}
+
+func TestJSONLoggerMasking(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewJSONLogger(&buf, "42")
+	logger.SetMasker(NewMasker("task", "1234"))
+	logger.Log(&event.Event{Type: event.TaskStart, Task: "task"})
+	logger.SetTask("task", false)
+	_, _ = logger.Write([]byte(`{"task":"my task","n":12345}` + "\n"))
+	logger.Log(&event.Event{Type: event.TaskFinish, Task: "task", DurationMs: 1234, Error: "task failed"})
+
+	var events []*event.Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e event.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		events = append(events, &e)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events but got %d:\n%s", len(events), buf.String())
+	}
+	if events[0].Type != event.TaskStart || events[0].Task != "task" {
+		t.Errorf("expected the event type and task name to be left intact but got %+v", events[0])
+	}
+	if events[1].Line != `{"****":"my ****","n":****5}` {
+		t.Errorf("expected the secrets to be masked in the line but got %q", events[1].Line)
+	}
+	if !reflect.DeepEqual(events[1].Data, json.RawMessage(`{"n":12345,"task":"my ****"}`)) {
+		t.Errorf("expected only the string values to be masked in the data but got %s", events[1].Data)
+	}
+	if events[2].DurationMs != 1234 || events[2].Error != "**** failed" {
+		t.Errorf("expected only the error to be masked but got %+v", events[2])
+	}
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces secret values in output.
+const Mask = "****"
+
+// minSecretLength is the length below which values are not masked, since masking them would
+// mangle ordinary output while hiding little.
+const minSecretLength = 4
+
+// NewMasker creates a Masker for the given secrets.
+func NewMasker(secrets ...string) *Masker {
+	m := &Masker{}
+	m.Add(secrets...)
+	return m
+}
+
+// Masker replaces secret values with Mask. A nil Masker masks nothing.
+type Masker struct {
+	mu       sync.RWMutex
+	secrets  map[string]bool
+	replacer *strings.Replacer
+}
+
+// Add registers secret values. Their JSON-escaped forms are masked as well, so that secrets are
+// also masked inside JSON strings.
+func (m *Masker) Add(secrets ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.secrets == nil {
+		m.secrets = make(map[string]bool)
+	}
+	added := false
+	for _, secret := range secrets {
+		if len(secret) < minSecretLength || m.secrets[secret] {
+			continue
+		}
+		m.secrets[secret] = true
+		added = true
+
+		if escaped, err := json.Marshal(secret); err == nil {
+			m.secrets[string(escaped[1:len(escaped)-1])] = true
+		}
+	}
+	if !added {
+		return
+	}
+
+	// longer secrets go first so that a secret containing another is masked entirely
+	all := make([]string, 0, len(m.secrets))
+	for secret := range m.secrets {
+		all = append(all, secret)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if len(all[i]) != len(all[j]) {
+			return len(all[i]) > len(all[j])
+		}
+		return all[i] < all[j]
+	})
+
+	pairs := make([]string, 0, 2*len(all))
+	for _, secret := range all {
+		pairs = append(pairs, secret, Mask)
+	}
+	m.replacer = strings.NewReplacer(pairs...)
+}
+
+// Mask returns s with the secrets replaced.
+func (m *Masker) Mask(s string) string {
+	if m == nil {
+		return s
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.replacer == nil {
+		return s
+	}
+	return m.replacer.Replace(s)
+}
+
+// Pending returns the number of bytes at the end of p which may be the beginning of a secret, so
+// that they can be held until the output which follows shows whether they are.
+func (m *Masker) Pending(p []byte) int {
+	if m == nil {
+		return 0
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cut := len(p)
+	for secret := range m.secrets {
+		n := len(secret) - 1
+		if n > len(p) {
+			n = len(p)
+		}
+		for ; n > 0 && len(p)-n < cut; n-- {
+			if bytes.HasSuffix(p, []byte(secret[:n])) {
+				cut = len(p) - n
+				break
+			}
+		}
+	}
+
+	// a secret which is complete may still begin before the cut and end after it
+	for moved := true; moved; {
+		moved = false
+		for secret := range m.secrets {
+			start, end := cut-len(secret)+1, cut+len(secret)-1
+			if start < 0 {
+				start = 0
+			}
+			if end > len(p) {
+				end = len(p)
+			}
+			if start >= end {
+				continue
+			}
+			if i := bytes.Index(p[start:end], []byte(secret)); i >= 0 {
+				cut = start + i
+				moved = true
+			}
+		}
+	}
+	return len(p) - cut
+}
+
+// MaskBytes returns p with the secrets replaced.
+func (m *Masker) MaskBytes(p []byte) []byte {
+	if m == nil {
+		return p
+	}
+
+	m.mu.RLock()
+	empty := m.replacer == nil
+	m.mu.RUnlock()
+	if empty {
+		return p
+	}
+	return []byte(m.Mask(string(p)))
+}
@@ -1,9 +1,23 @@
 package internal
 
 import (
+	"fmt"
 	"io"
+	"time"
 )
 
+// AbsoluteTimestamps formats the wall-clock time of a line.
+func AbsoluteTimestamps(t time.Time) string {
+	return t.Format("15:04:05.000")
+}
+
+// RelativeTimestamps formats the time of a line relative to start.
+func RelativeTimestamps(start time.Time) func(time.Time) string {
+	return func(t time.Time) string {
+		return fmt.Sprintf("%11.3fs", t.Sub(start).Seconds())
+	}
+}
+
 // NewPrefixWriter creates a PrefixWriter.
 func NewPrefixWriter(w io.Writer) *PrefixWriter {
 	return &PrefixWriter{
@@ -16,9 +30,11 @@ func NewPrefixWriter(w io.Writer) *PrefixWriter {
 // PrefixWriter wraps an io.Writer to automatically add a prefix at the beginning
 // of every line.
 type PrefixWriter struct {
-	w      io.Writer
-	prefix []byte
-	nl     bool
+	w          io.Writer
+	prefix     []byte
+	timestamps func(time.Time) string
+	stripANSI  bool
+	nl         bool
 
 	out []byte
 }
@@ -28,12 +44,38 @@ func (w *PrefixWriter) SetPrefix(prefix []byte) {
 	w.prefix = prefix
 }
 
+// SetTimestamps sets how the time of each line is formatted. The timestamp is written at the
+// beginning of the line, before the prefix. A nil func disables timestamps.
+func (w *PrefixWriter) SetTimestamps(timestamps func(time.Time) string) {
+	w.timestamps = timestamps
+}
+
+// SetStripANSI sets whether ANSI escape sequences, such as colors, are removed from the output.
+func (w *PrefixWriter) SetStripANSI(strip bool) {
+	w.stripANSI = strip
+}
+
 func (w *PrefixWriter) Write(p []byte) (n int, err error) {
-	for _, c := range p {
+	return w.WriteTimed(p, time.Now())
+}
+
+// WriteTimed implements the TimedWriter interface, stamping the lines in p with t.
+func (w *PrefixWriter) WriteTimed(p []byte, t time.Time) (n int, err error) {
+	data := p
+	if w.stripANSI {
+		data = ANSIEscape.ReplaceAll(data, nil)
+	}
+	for _, c := range data {
 		if w.nl {
+			if w.timestamps != nil {
+				_, err = io.WriteString(w.w, w.timestamps(t)+" ")
+				if err != nil {
+					return 0, err
+				}
+			}
 			_, err = w.w.Write(w.prefix)
 			if err != nil {
-				return n, err
+				return 0, err
 			}
 			w.nl = false
 		}
@@ -41,12 +83,11 @@ func (w *PrefixWriter) Write(p []byte) (n int, err error) {
 		w.out[0] = c
 		_, err = w.w.Write(w.out)
 		if err != nil {
-			return n, err
+			return 0, err
 		}
 
-		n++
 		w.nl = c == '\n'
 	}
 
-	return n, nil
+	return len(p), nil
 }
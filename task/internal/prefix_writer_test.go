@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPrefixWriterTimestamps(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	w := NewPrefixWriter(&buf)
+	w.SetPrefix([]byte("| "))
+	w.SetTimestamps(RelativeTimestamps(start))
+
+	var rec Recorder
+	_, _ = rec.WriteTimed([]byte("first\nsec"), start.Add(1500*time.Millisecond))
+	_, _ = rec.WriteTimed([]byte("ond\n"), start.Add(2*time.Second))
+	_, _ = rec.WriteTimed([]byte("third\n"), start.Add(62*time.Second))
+	if err := rec.ReplayTo(w); err != nil {
+		t.Fatal(err)
+	}
+
+	w.SetTimestamps(AbsoluteTimestamps)
+	_, _ = w.WriteTimed([]byte("fourth\n"), start)
+
+	expected := `      1.500s | first
+      1.500s | second
+     62.000s | third
+03:04:05.000 | fourth
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestPrefixWriterStripANSI(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewPrefixWriter(&buf)
+	w.SetPrefix([]byte("| "))
+	w.SetStripANSI(true)
+
+	_, _ = w.Write([]byte("\x1b[1;32mok\x1b[0m\n"))
+	if buf.String() != "| ok\n" {
+		t.Fatalf("expected the colors to be stripped, but got %q", buf.String())
+	}
+}
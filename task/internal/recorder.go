@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// RecorderMemoryLimit is the number of bytes a Recorder keeps in memory before spilling its
+// recording to a temporary file.
+var RecorderMemoryLimit = 1 << 20
+
+// TimedWriter is implemented by writers which annotate lines with the time they were written.
+type TimedWriter interface {
+	WriteTimed(p []byte, t time.Time) (int, error)
+}
+
+// Recorder buffers writes along with the time they were made, so that they can be replayed later
+// without losing their timestamps. Once the recording exceeds RecorderMemoryLimit, it is moved to a
+// temporary file which is removed by Close.
+type Recorder struct {
+	chunks []recordedChunk
+	size   int
+	spill  *os.File
+}
+
+type recordedChunk struct {
+	p []byte
+	t time.Time
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	return r.WriteTimed(p, time.Now())
+}
+
+// WriteTimed implements the TimedWriter interface.
+func (r *Recorder) WriteTimed(p []byte, t time.Time) (int, error) {
+	if r.spill != nil {
+		if err := writeChunk(r.spill, p, t); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	r.chunks = append(r.chunks, recordedChunk{p: append([]byte(nil), p...), t: t})
+	r.size += len(p)
+	if r.size > RecorderMemoryLimit {
+		// failing to spill only costs memory, so the recording carries on in memory
+		_ = r.spillChunks()
+	}
+	return len(p), nil
+}
+
+// spillChunks moves the chunks recorded in memory to a temporary file.
+func (r *Recorder) spillChunks() error {
+	f, err := ioutil.TempFile("", "goke-output-")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, c := range r.chunks {
+		if err := writeChunk(w, c.p, c.t); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	r.spill = f
+	r.chunks = nil
+	r.size = 0
+	return nil
+}
+
+// ReplayTo writes the recorded chunks to w, preserving their times when w is a TimedWriter.
+func (r *Recorder) ReplayTo(w io.Writer) error {
+	tw, timed := w.(TimedWriter)
+	replay := func(p []byte, t time.Time) error {
+		var err error
+		if timed {
+			_, err = tw.WriteTimed(p, t)
+		} else {
+			_, err = w.Write(p)
+		}
+		return err
+	}
+
+	if r.spill == nil {
+		for _, c := range r.chunks {
+			if err := replay(c.p, c.t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, err := r.spill.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// later writes are appended to the recording
+	defer func() { _, _ = r.spill.Seek(0, io.SeekEnd) }()
+
+	br := bufio.NewReader(r.spill)
+	for {
+		p, t, err := readChunk(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := replay(p, t); err != nil {
+			return err
+		}
+	}
+}
+
+// Close discards the recording, removing its temporary file.
+func (r *Recorder) Close() error {
+	r.chunks = nil
+	r.size = 0
+	if r.spill == nil {
+		return nil
+	}
+
+	name := r.spill.Name()
+	err := r.spill.Close()
+	r.spill = nil
+	if rerr := os.Remove(name); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// writeChunk encodes a chunk as its time in nanoseconds and its length, followed by its bytes.
+func writeChunk(w io.Writer, p []byte, t time.Time) error {
+	var header [12]byte
+	binary.BigEndian.PutUint64(header[:8], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(p)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(p)
+	return err
+}
+
+func readChunk(r io.Reader) ([]byte, time.Time, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	p := make([]byte, binary.BigEndian.Uint32(header[8:]))
+	if _, err := io.ReadFull(r, p); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, time.Time{}, err
+	}
+	return p, time.Unix(0, int64(binary.BigEndian.Uint64(header[:8]))), nil
+}
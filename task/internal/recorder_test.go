@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type timedBuffer struct {
+	bytes.Buffer
+	times []time.Time
+}
+
+func (b *timedBuffer) WriteTimed(p []byte, t time.Time) (int, error) {
+	b.times = append(b.times, t)
+	return b.Write(p)
+}
+
+func TestRecorderSpill(t *testing.T) {
+	RecorderMemoryLimit = 16
+	defer func() { RecorderMemoryLimit = 1 << 20 }()
+
+	start := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	var rec Recorder
+	for i := 0; i < 10; i++ {
+		_, _ = rec.WriteTimed([]byte("a line of output\n"), start.Add(time.Duration(i)*time.Second))
+	}
+	if rec.spill == nil || len(rec.chunks) != 0 {
+		t.Fatal("expected the recording to spill to a file")
+	}
+	name := rec.spill.Name()
+
+	var out timedBuffer
+	if err := rec.ReplayTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != strings.Repeat("a line of output\n", 10) {
+		t.Fatalf("unexpected replay:\n%s", out.String())
+	}
+	if !out.times[9].Equal(start.Add(9 * time.Second)) {
+		t.Fatalf("expected the times to be preserved, but got %v", out.times[9])
+	}
+
+	// writes after a replay are appended
+	_, _ = rec.Write([]byte("more\n"))
+	out.Reset()
+	_ = rec.ReplayTo(&out)
+	if !strings.HasSuffix(out.String(), "output\nmore\n") || strings.Count(out.String(), "\n") != 11 {
+		t.Fatalf("unexpected replay:\n%s", out.String())
+	}
+
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed", name)
+	}
+}
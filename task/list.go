@@ -0,0 +1,109 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TaskListing is the machine-readable description of a task produced by -list=json.
+type TaskListing struct {
+	Name            string       `json:"name"`
+	Description     string       `json:"description"`
+	LongDescription string       `json:"longDescription,omitempty"`
+	Aliases         []string     `json:"aliases"`
+	Deprecation     *Deprecation `json:"deprecation,omitempty"`
+	Args            []ArgListing `json:"args"`
+	Dependencies    []string     `json:"dependencies"`
+	DeferredTasks   []string     `json:"deferredTasks"`
+	FinalizedBy     []string     `json:"finalizedBy"`
+	Matrix          []MatrixAxis `json:"matrix"`
+	Resources       []string     `json:"resources"`
+	MustRunAfter    []string     `json:"mustRunAfter"`
+	ShouldRunAfter  []string     `json:"shouldRunAfter"`
+	Hidden          bool         `json:"hidden"`
+	Quiet           bool         `json:"quiet"`
+	AutoNamespace   bool         `json:"autoNamespace"`
+}
+
+// ArgListing is the machine-readable description of a declared task argument.
+type ArgListing struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Default  string `json:"default,omitempty"`
+	Secret   bool   `json:"secret,omitempty"`
+}
+
+// RuleListing is the machine-readable description of a task rule produced by -list=json.
+type RuleListing struct {
+	Pattern     string `json:"pattern"`
+	Description string `json:"description"`
+}
+
+// listTasks builds the listing of every task in the registry, including hidden ones.
+func listTasks(registry *Registry) []TaskListing {
+	var listings []TaskListing
+	for _, t := range registry.Tasks() {
+		listing := TaskListing{
+			Name:            t.Name(),
+			Description:     t.Description(),
+			LongDescription: taskLongDescription(t),
+			Aliases:         append([]string{}, taskAliases(t)...),
+			Deprecation:     taskDeprecation(t),
+			Args:            []ArgListing{},
+			Dependencies:    append([]string{}, t.Dependencies()...),
+			DeferredTasks:   append([]string{}, t.DeferredTasks()...),
+			FinalizedBy:     append([]string{}, taskFinalizedBy(t)...),
+			Matrix:          append([]MatrixAxis{}, taskMatrix(t)...),
+			Resources:       append([]string{}, taskResources(t)...),
+			MustRunAfter:    append([]string{}, taskMustRunAfter(t)...),
+			ShouldRunAfter:  append([]string{}, taskShouldRunAfter(t)...),
+			Hidden:          t.Hidden(),
+			Quiet:           taskQuiet(t),
+			AutoNamespace:   registry.isAutoNamespace(t),
+		}
+		for _, a := range t.DeclaredArgs() {
+			listing.Args = append(listing.Args, ArgListing{
+				Name:     a.Name,
+				Required: a.IsRequired(),
+				Default:  maskArg(a, a.Default),
+				Secret:   a.Secret,
+			})
+		}
+		listings = append(listings, listing)
+	}
+
+	return listings
+}
+
+// printList writes the registry in the requested format. The default format prints
+// the names of the visible tasks, one per line.
+func printList(registry *Registry, format string, out io.Writer) error {
+	switch format {
+	case trueString, "text":
+		for _, t := range registry.Tasks() {
+			if !t.Hidden() {
+				fmt.Fprintln(out, t.Name())
+			}
+		}
+		return nil
+	case "json":
+		listings := listTasks(registry)
+		if listings == nil {
+			listings = []TaskListing{}
+		}
+		rules := []RuleListing{}
+		for _, rl := range registry.rules {
+			rules = append(rules, RuleListing{
+				Pattern:     rl.pattern,
+				Description: rl.description,
+			})
+		}
+		return json.NewEncoder(out).Encode(struct {
+			Tasks []TaskListing `json:"tasks"`
+			Rules []RuleListing `json:"rules"`
+		}{listings, rules})
+	default:
+		return fmt.Errorf("unknown list format %q", format)
+	}
+}
@@ -0,0 +1,56 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPrintList(t *testing.T) {
+	registry := NewRegistry(WithAutoNamespaces(true))
+	registry.Declare("build").Description("build it").DependsOn("sa").Do(makeExecutor("build", false))
+	registry.Declare("sa:lint").RequiredArg("pkg").OptionalArg("fix").Defer("build").Do(makeExecutor("sa:lint", false))
+	registry.Declare("secret").Hide().Do(makeExecutor("secret", false))
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := printList(registry, trueString, &buf); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		if buf.String() != "build\nsa\nsa:lint\n" {
+			t.Fatalf("unexpected listing:\n%s", buf.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := printList(registry, "json", &buf); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		var result struct {
+			Tasks []TaskListing `json:"tasks"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+			t.Fatalf("expected valid JSON, but got %v", err)
+		}
+
+		expected := []TaskListing{
+			{Name: "build", Description: "build it", Aliases: []string{}, Args: []ArgListing{}, Dependencies: []string{"sa"}, DeferredTasks: []string{}, FinalizedBy: []string{}, Matrix: []MatrixAxis{}, Resources: []string{}, MustRunAfter: []string{}, ShouldRunAfter: []string{}},
+			{Name: "sa", Aliases: []string{}, Args: []ArgListing{}, Dependencies: []string{"sa:lint"}, DeferredTasks: []string{}, FinalizedBy: []string{}, Matrix: []MatrixAxis{}, Resources: []string{}, MustRunAfter: []string{}, ShouldRunAfter: []string{}, AutoNamespace: true},
+			{Name: "sa:lint", Aliases: []string{}, Args: []ArgListing{{Name: "pkg", Required: true}, {Name: "fix"}}, Dependencies: []string{}, DeferredTasks: []string{"build"}, FinalizedBy: []string{}, Matrix: []MatrixAxis{}, Resources: []string{}, MustRunAfter: []string{}, ShouldRunAfter: []string{}},
+			{Name: "secret", Aliases: []string{}, Args: []ArgListing{}, Dependencies: []string{}, DeferredTasks: []string{}, FinalizedBy: []string{}, Matrix: []MatrixAxis{}, Resources: []string{}, MustRunAfter: []string{}, ShouldRunAfter: []string{}, Hidden: true},
+		}
+		if !reflect.DeepEqual(result.Tasks, expected) {
+			t.Fatalf("expected %+v, but got %+v", expected, result.Tasks)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := printList(registry, "yaml", &bytes.Buffer{}); err == nil {
+			t.Fatal("expected an error, but got none")
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+const matrixNamespace = "matrix"
+
+// MatrixAxis is an argument and the values a task is run with in a matrix.
+type MatrixAxis struct {
+	Arg    string   `json:"arg"`
+	Values []string `json:"values"`
+}
+
+// matrixAxes returns the axes the task runs with. Axes given on the command line replace the
+// task's own axes for the same argument, and apply only to tasks declaring the argument.
+func (r *runner) matrixAxes(t Task) []MatrixAxis {
+	axes := append([]MatrixAxis{}, taskMatrix(t)...)
+	for _, axis := range r.opts.matrix {
+		replaced := false
+		for i := range axes {
+			if axes[i].Arg == axis.Arg {
+				axes[i] = axis
+				replaced = true
+			}
+		}
+		if !replaced && declaresArg(t, axis.Arg) {
+			axes = append(axes, axis)
+		}
+	}
+	return axes
+}
+
+// runMatrix runs the task once for every combination of the axes' values. The task is reported
+// as started before its cells run in parallel, and each cell's output is reported separately once
+// all have finished, followed by the task's own result.
+func (r *runner) runMatrix(t Task, executor Executor, axes []MatrixAxis, finalized *TaskResult) *TaskResult {
+	r.rep.taskStarted(t)
+
+	cells := matrixCells(axes)
+	results := make([]*TaskResult, len(cells))
+	outputs := make([]internal.Recorder, len(cells))
+
+	startTime := time.Now()
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, cell := range cells {
+		name := matrixCellName(t.Name(), cell)
+
+		cellArgs := r.opts.args.clone()
+		for _, v := range cell {
+			cellArgs.set(t.Name(), v.arg, v.value)
+		}
+		taskArgs, err := argsForTask(t, cellArgs)
+		if err != nil {
+			results[i] = newTaskResult(name, err, 0)
+			continue
+		}
+
+		ctx := NewContext(context.Background(), &outputs[i], taskArgs, r.contextParams(t, finalized)...)
+		wg.Add(1)
+		go func(i int, name string, ctx *Context) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cellStartTime := time.Now()
+			err := runExecutor(executor, ctx)
+			results[i] = newTaskResult(name, err, time.Since(cellStartTime))
+		}(i, name, ctx)
+	}
+	wg.Wait()
+
+	var failedCells []string
+	for i, result := range results {
+		cellTask := &matrixCell{Task: t, name: result.Task}
+		r.rep.taskStarted(cellTask)
+		_ = outputs[i].ReplayTo(r.rep.writer())
+		_ = outputs[i].Close()
+		r.rep.taskFinished(cellTask, result)
+		r.results = append(r.results, result)
+		if result.Err != nil {
+			failedCells = append(failedCells, result.Task)
+		}
+	}
+
+	var err error
+	if len(failedCells) > 0 {
+		err = fmt.Errorf("matrix cell(s) %s failed", failedCells)
+	}
+	result := newTaskResult(t.Name(), err, time.Since(startTime))
+	r.rep.taskFinished(t, result)
+	r.results = append(r.results, result)
+	return result
+}
+
+type matrixValue struct {
+	arg   string
+	value string
+}
+
+// matrixCells returns the cartesian product of the axes' values.
+func matrixCells(axes []MatrixAxis) [][]matrixValue {
+	cells := [][]matrixValue{nil}
+	for _, axis := range axes {
+		var next [][]matrixValue
+		for _, cell := range cells {
+			for _, value := range axis.Values {
+				next = append(next, append(append([]matrixValue{}, cell...), matrixValue{arg: axis.Arg, value: value}))
+			}
+		}
+		cells = next
+	}
+	return cells
+}
+
+// matrixCellName names a cell like dist[goos=linux,goarch=arm64].
+func matrixCellName(taskName string, cell []matrixValue) string {
+	values := make([]string, len(cell))
+	for i, v := range cell {
+		values[i] = v.arg + "=" + v.value
+	}
+	return taskName + "[" + strings.Join(values, ",") + "]"
+}
+
+func declaresArg(t Task, name string) bool {
+	for _, da := range t.DeclaredArgs() {
+		if da.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matrixCell is a single cell of a task run in a matrix.
+type matrixCell struct {
+	Task
+
+	name string
+}
+
+func (t *matrixCell) Name() string {
+	return t.name
+}
+
+func (t *matrixCell) Quiet() bool {
+	return taskQuiet(t.Task)
+}
@@ -0,0 +1,142 @@
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+func TestMatrixCells(t *testing.T) {
+	cells := matrixCells([]MatrixAxis{
+		{Arg: "goos", Values: []string{"linux", "darwin"}},
+		{Arg: "goarch", Values: []string{"amd64", "arm64"}},
+	})
+
+	var names []string
+	for _, cell := range cells {
+		names = append(names, matrixCellName("dist", cell))
+	}
+
+	expected := []string{
+		"dist[goos=linux,goarch=amd64]",
+		"dist[goos=linux,goarch=arm64]",
+		"dist[goos=darwin,goarch=amd64]",
+		"dist[goos=darwin,goarch=arm64]",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %v, but got %v", expected, names)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	reg := NewRegistry()
+	reg.Declare("dist").RequiredArg("goos").OptionalArg("goarch").Matrix("goarch", "amd64").Do(func(ctx *Context) error {
+		mu.Lock()
+		ran = append(ran, ctx.Get("goos")+"/"+ctx.Get("goarch"))
+		mu.Unlock()
+		if ctx.Get("goos") == "windows" && ctx.Get("goarch") == "arm64" {
+			return fmt.Errorf("unsupported")
+		}
+		return nil
+	})
+
+	testCases := []struct {
+		args          []string
+		shouldFailRun bool
+		expected      []string
+	}{
+		{[]string{"dist", "-goos=linux"}, false, []string{"linux/amd64"}},
+		{[]string{"dist", "-matrix:goos=linux,darwin", "-matrix:goarch=amd64,arm64"}, false, []string{"darwin/amd64", "darwin/arm64", "linux/amd64", "linux/arm64"}},
+		// a failing cell does not prevent the other cells from running
+		{[]string{"dist", "-matrix:goos=windows,linux", "-matrix:goarch=arm64,amd64"}, true, []string{"linux/amd64", "linux/arm64", "windows/amd64", "windows/arm64"}},
+		// goos is required, but missing from the cells
+		{[]string{"dist", "-matrix:goarch=arm64"}, true, nil},
+		// an empty axis would run no cells
+		{[]string{"dist", "-goos=linux", "-matrix:goarch="}, true, nil},
+		{[]string{"dist", "-goos=linux", "-matrix:goarch=amd64,"}, true, nil},
+	}
+
+	for _, tc := range testCases {
+		ran = nil
+		err := Run(reg, tc.args)
+		if err == nil && tc.shouldFailRun {
+			t.Fatalf("%v: expected error", tc.args)
+		} else if err != nil && !tc.shouldFailRun {
+			t.Fatalf("%v: expected no error, but got %v", tc.args, err)
+		}
+
+		sort.Strings(ran)
+		if !reflect.DeepEqual(ran, tc.expected) {
+			t.Fatalf("%v: expected cells %v, but got %v", tc.args, tc.expected, ran)
+		}
+	}
+
+	reg = NewRegistry(WithShouldErrorOnUnusedArgs(true))
+	declare(reg, "build", false)
+	if err := Run(reg, []string{"build", "-matrix:goos=linux"}); err == nil {
+		t.Fatal("expected an error for an unused matrix argument")
+	}
+
+	// the task itself is reported after its cells
+	reg = NewRegistry()
+	reg.Declare("dist").Matrix("goos", "linux", "darwin").Do(func(ctx *Context) error { return nil })
+	opts, err := parseArgs([]string{"dist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := sortTasksToRun(reg.Tasks(), opts.taskNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	rep := &resultsReporter{reporter: &humanReporter{w: internal.NewPrefixWriter(&out)}}
+	if err := runTasks(reg, opts, tasks, rep); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	var names []string
+	for _, result := range rep.results {
+		names = append(names, result.Task)
+	}
+	expected := []string{"dist[goos=linux]", "dist[goos=darwin]", "dist"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected results %v, but got %v", expected, names)
+	}
+	// the task is reported as started before its cells run
+	expected = []string{"dist", "dist[goos=linux]", "dist[goos=darwin]"}
+	if !reflect.DeepEqual(rep.started, expected) {
+		t.Fatalf("expected the tasks to start in the order %v, but got %v", expected, rep.started)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a matrix axis without values")
+		}
+	}()
+	NewRegistry().Declare("dist").Matrix("goos")
+}
+
+// resultsReporter records the started tasks and the results of the run.
+type resultsReporter struct {
+	reporter
+
+	started []string
+	results []*TaskResult
+}
+
+func (r *resultsReporter) taskStarted(t Task) {
+	r.started = append(r.started, t.Name())
+	r.reporter.taskStarted(t)
+}
+
+func (r *resultsReporter) runCompleted(elapsed time.Duration, results []*TaskResult, failedTasks []string) {
+	r.results = results
+	r.reporter.runCompleted(elapsed, results, failedTasks)
+}
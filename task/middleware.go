@@ -0,0 +1,85 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timed is a middleware which logs how long the executor took to run.
+func Timed() Middleware {
+	return func(t Task, next Executor) Executor {
+		return func(ctx *Context) error {
+			startTime := time.Now()
+			err := next(ctx)
+			ctx.Logf("%s took %s\n", t.Name(), time.Since(startTime))
+			return err
+		}
+	}
+}
+
+// Retry is a middleware which runs the executor up to attempts times until it succeeds. The
+// executor always runs at least once.
+func Retry(attempts int) Middleware {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return func(t Task, next Executor) Executor {
+		return func(ctx *Context) error {
+			var err error
+			for i := 0; i < attempts; i++ {
+				if i > 0 {
+					ctx.Logf("retrying %s (%d/%d): %v\n", t.Name(), i+1, attempts, err)
+				}
+				if err = next(ctx); err == nil || ctx.Err() != nil {
+					return err
+				}
+			}
+			return err
+		}
+	}
+}
+
+// Logged is a middleware which logs the arguments the executor runs with and the error it returns.
+// Values of secret arguments are masked like all other output.
+func Logged() Middleware {
+	return func(t Task, next Executor) Executor {
+		return func(ctx *Context) error {
+			args := ctx.CopyArgs()
+			names := make([]string, 0, len(args))
+			for name := range args {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			msg := "running " + t.Name()
+			if len(names) > 0 {
+				pairs := make([]string, len(names))
+				for i, name := range names {
+					pairs[i] = fmt.Sprintf("%s=%s", name, args[name])
+				}
+				msg += " with " + strings.Join(pairs, " ")
+			}
+			ctx.Logln(msg)
+
+			err := next(ctx)
+			if err != nil {
+				ctx.Logf("%s failed: %v\n", t.Name(), err)
+			}
+			return err
+		}
+	}
+}
+
+// Locked is a middleware which holds the lock while the executor runs.
+func Locked(l sync.Locker) Middleware {
+	return func(_ Task, next Executor) Executor {
+		return func(ctx *Context) error {
+			l.Lock()
+			defer l.Unlock()
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func recordingMiddleware(name string) Middleware {
+	return func(t Task, next Executor) Executor {
+		return func(ctx *Context) error {
+			runOrder = append(runOrder, name+">"+t.Name())
+			err := next(ctx)
+			runOrder = append(runOrder, name+"<"+t.Name())
+			return err
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	registry := NewRegistry()
+	registry.Use(recordingMiddleware("r1"), recordingMiddleware("r2"))
+	declare(registry, "dep", false)
+	declare(registry, "build", false).DependsOn("dep").Wrap(recordingMiddleware("b"))
+	registry.Declare("agg").DependsOn("build")
+
+	runOrder = []string{}
+	if err := Run(registry, []string{"agg"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	expected := []string{
+		"r1>dep", "r2>dep", "dep", "r2<dep", "r1<dep",
+		"r1>build", "r2>build", "b>build", "build", "b<build", "r2<build", "r1<build",
+	}
+	if !reflect.DeepEqual(runOrder, expected) {
+		t.Fatalf("expected run order %v but got %v", expected, runOrder)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	registry := NewRegistry()
+	attempts := 0
+	registry.Declare("flaky").Wrap(Retry(3)).Do(func(ctx *Context) error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("attempt %d failed", attempts)
+		}
+		return nil
+	})
+
+	if err := Run(registry, []string{"flaky"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, but got %d", attempts)
+	}
+}
+
+func TestRetryAtLeastOnce(t *testing.T) {
+	registry := NewRegistry()
+	attempts := 0
+	registry.Declare("once").Wrap(Retry(0)).Do(func(ctx *Context) error {
+		attempts++
+		return fmt.Errorf("attempt %d failed", attempts)
+	})
+
+	if err := Run(registry, []string{"once"}); err == nil {
+		t.Fatal("expected the failure to be returned, but got no error")
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, but got %d", attempts)
+	}
+}
+
+func TestLogged(t *testing.T) {
+	var buf bytes.Buffer
+	task := build("deploy").task
+	executor := Logged()(task, func(ctx *Context) error {
+		return fmt.Errorf("no target")
+	})
+
+	ctx := NewContext(context.Background(), &buf, map[string]string{"target": "prod", "env": "eu"})
+	if err := executor(ctx); err == nil {
+		t.Fatal("expected an error, but got none")
+	}
+
+	expected := "running deploy with env=eu target=prod\ndeploy failed: no target\n"
+	if buf.String() != expected {
+		t.Fatalf("expected %q but got %q", expected, buf.String())
+	}
+}
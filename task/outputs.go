@@ -0,0 +1,71 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// outputStore holds the values published by tasks during a run.
+type outputStore struct {
+	mu     sync.Mutex
+	values map[string]map[string]interface{}
+}
+
+func newOutputStore() *outputStore {
+	return &outputStore{
+		values: make(map[string]map[string]interface{}),
+	}
+}
+
+func (s *outputStore) set(taskName string, key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values, ok := s.values[taskName]
+	if !ok {
+		values = make(map[string]interface{})
+		s.values[taskName] = values
+	}
+	values[key] = value
+}
+
+func (s *outputStore) get(taskName string, key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[taskName][key]
+	return v, ok
+}
+
+// outputResolver resolves the name of a task whose outputs are read to its canonical name,
+// returning an error if the reading task is not allowed to read them.
+type outputResolver func(producer string) (string, error)
+
+// dependencyResolver returns an outputResolver which allows reading the outputs of the task's
+// transitive dependencies, and of the finalized task when the task runs as a finalizer.
+func dependencyResolver(allTasks map[string]Task, t Task, finalized *TaskResult) outputResolver {
+	deps := make(map[string]bool)
+	var visit func(Task)
+	visit = func(t Task) {
+		for _, name := range t.Dependencies() {
+			if dep, ok := allTasks[strings.ToLower(name)]; ok && !deps[dep.Name()] {
+				deps[dep.Name()] = true
+				visit(dep)
+			}
+		}
+	}
+	visit(t)
+	if finalized != nil {
+		deps[finalized.Task] = true
+	}
+
+	return func(producer string) (string, error) {
+		p, ok := allTasks[strings.ToLower(producer)]
+		if !ok {
+			return "", fmt.Errorf("unknown task '%s'", producer)
+		}
+		if !deps[p.Name()] {
+			return "", fmt.Errorf("task '%s' must depend on '%s' to read its outputs", t.Name(), p.Name())
+		}
+		return p.Name(), nil
+	}
+}
@@ -0,0 +1,241 @@
+package task
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// errPickerCanceled is returned by pickTasks when the picker is quit without picking tasks.
+var errPickerCanceled = errors.New("canceled")
+
+// The keys the picker responds to, other than those typed into the filter.
+const (
+	keyInterrupt = 0x03
+	keyEOT       = 0x04
+	keyBackspace = 0x08
+	keyEscape    = 0x1b
+	keyDelete    = 0x7f
+)
+
+// pickTasks interactively asks which of the visible tasks to run, reading each key as it is
+// pressed. Typing filters the list, the up and down arrows move between the listed tasks, space
+// or tab toggles the selection, and enter runs the selected tasks, or the highlighted task when
+// none are selected. Escape or ctrl-c quits, returning errPickerCanceled.
+//
+// restore returns the terminal to reading lines, after which values for required arguments of
+// the picked tasks and their dependencies are prompted for and added to args. It may be nil.
+func pickTasks(ui *TUI, registry *Registry, in io.Reader, out io.Writer, args globalArgs, restore func()) ([]string, error) {
+	var tasks []Task
+	for _, t := range registry.Tasks() {
+		if !t.Hidden() {
+			tasks = append(tasks, t)
+		}
+	}
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+
+	rd := bufio.NewReader(in)
+	p := &picker{ui: ui, out: out, tasks: tasks, selected: make(map[string]bool)}
+	names, err := p.pick(rd)
+	if err != nil {
+		return nil, err
+	}
+
+	if restore != nil {
+		restore()
+	}
+	if err := promptRequiredArgs(ui, registry, rd, out, names, args); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// picker is the state of the task picker.
+type picker struct {
+	ui    *TUI
+	out   io.Writer
+	tasks []Task
+
+	filter   string
+	cursor   int
+	selected map[string]bool
+
+	// lines is the number of lines last drawn, which are cleared when redrawing.
+	lines int
+}
+
+// pick handles keys until tasks are picked.
+func (p *picker) pick(rd *bufio.Reader) ([]string, error) {
+	for {
+		p.draw()
+
+		key, err := rd.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		visible := p.visible()
+		switch key {
+		case keyInterrupt, keyEOT:
+			return nil, errPickerCanceled
+		case keyEscape:
+			// the arrow keys send escape sequences, whereas escape itself arrives alone
+			if rd.Buffered() == 0 {
+				return nil, errPickerCanceled
+			}
+			if next, _ := rd.ReadByte(); next != '[' {
+				return nil, errPickerCanceled
+			}
+			switch arrow, _ := rd.ReadByte(); arrow {
+			case 'A':
+				p.cursor--
+			case 'B':
+				p.cursor++
+			}
+		case '\r', '\n':
+			names := p.picked()
+			if len(names) == 0 && len(visible) > 0 {
+				names = []string{visible[p.cursor].Name()}
+			}
+			if len(names) > 0 {
+				return names, nil
+			}
+		case ' ', '\t':
+			if len(visible) > 0 {
+				name := visible[p.cursor].Name()
+				p.selected[name] = !p.selected[name]
+			}
+		case keyBackspace, keyDelete:
+			if p.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(p.filter)
+				p.filter = p.filter[:len(p.filter)-size]
+				p.cursor = 0
+			}
+		default:
+			if key >= ' ' {
+				p.filter += string([]byte{key})
+				p.cursor = 0
+			}
+		}
+
+		if n := len(p.visible()); p.cursor >= n {
+			p.cursor = n - 1
+		}
+		if p.cursor < 0 {
+			p.cursor = 0
+		}
+	}
+}
+
+// visible returns the tasks matching the filter by name or description.
+func (p *picker) visible() []Task {
+	filter := strings.ToLower(p.filter)
+	var visible []Task
+	for _, t := range p.tasks {
+		if strings.Contains(strings.ToLower(t.Name()), filter) || strings.Contains(strings.ToLower(t.Description()), filter) {
+			visible = append(visible, t)
+		}
+	}
+	return visible
+}
+
+// picked returns the names of the selected tasks, in the order they're listed.
+func (p *picker) picked() []string {
+	var names []string
+	for _, t := range p.tasks {
+		if p.selected[t.Name()] {
+			names = append(names, t.Name())
+		}
+	}
+	return names
+}
+
+// draw replaces the lines last drawn with the listed tasks and the filter.
+func (p *picker) draw() {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat(clearLineUp, p.lines))
+
+	nameWidth := 0
+	for _, t := range p.tasks {
+		if len(t.Name()) > nameWidth {
+			nameWidth = len(t.Name())
+		}
+	}
+
+	lines := 0
+	visible := p.visible()
+	for i, t := range visible {
+		cursor := " "
+		if i == p.cursor {
+			cursor = p.ui.Highlight(">")
+		}
+		mark := "[ ]"
+		if p.selected[t.Name()] {
+			mark = p.ui.Success("[x]")
+		}
+		fmt.Fprintf(&sb, "%s %s %s  %s\n", cursor, mark, p.ui.Info(pad(t.Name(), nameWidth)), t.Description())
+		lines++
+	}
+	if len(visible) == 0 {
+		fmt.Fprintln(&sb, p.ui.Lowlight("  no tasks match"))
+		lines++
+	}
+	fmt.Fprintln(&sb, p.ui.Lowlight("type to filter, arrows to move, space to select, enter to run, esc to quit"))
+	fmt.Fprintln(&sb, p.ui.Highlight("filter")+"> "+p.filter)
+	p.lines = lines + 2
+
+	_, _ = io.WriteString(p.out, sb.String())
+}
+
+// promptRequiredArgs asks for the values of required arguments which have not been supplied.
+func promptRequiredArgs(ui *TUI, registry *Registry, rd *bufio.Reader, out io.Writer, names []string, args globalArgs) error {
+	tasks, err := sortTasksToRun(registry.Tasks(), names)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		for _, da := range t.DeclaredArgs() {
+			if !da.IsRequired() {
+				continue
+			}
+			if _, ok := taskScopedArg(t, args, da.Name); ok {
+				continue
+			}
+			if _, ok := args.get("", da.Name); ok {
+				continue
+			}
+
+			for {
+				fmt.Fprintf(out, "%s (required by %s): ", ui.Info(da.Name), t.Name())
+				value, err := readLine(rd)
+				if err != nil {
+					return err
+				}
+				if verr := da.Validator(da.Name, value); verr != nil {
+					fmt.Fprintln(out, ui.Error(verr.Error()))
+					continue
+				}
+				args.set(t.Name(), da.Name, value)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// readLine reads a trimmed line, returning io.EOF only when no more input is available.
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
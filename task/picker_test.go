@@ -0,0 +1,93 @@
+package task
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPickTasks(t *testing.T) {
+	reg := NewRegistry()
+	declare(reg, "build", false).Description("builds it")
+	declare(reg, "clean", false).Description("cleans up")
+	declare(reg, "deploy", false).Description("ships it").RequiredArg("env").DependsOn("build")
+	declare(reg, "publish", false).Description("publishes it").RequiredArg("token")
+	declare(reg, "internal", false).Hide()
+
+	t.Run("filter, select and prompt", func(t *testing.T) {
+		in := strings.NewReader(strings.Join([]string{
+			"zzz",          // filter
+			"\r",           // nothing matches, so this is ignored
+			"\x7f\x7f\x7f", // clear the filter
+			"dep",          // filter
+			" ",            // select deploy
+			"\x7f\x7f\x7f", // clear the filter
+			"\x1b[B",       // move to clean
+			" ",            // select clean
+			"\x1b[B\x1b[B", // move to publish
+			"  ",           // toggle publish on and off
+			"\r",           // run
+			"\n",           // env is required, so this is rejected
+			"prod\n",       // env
+		}, ""))
+		var out bytes.Buffer
+		args := globalArgs{}
+		restored := false
+
+		names, err := pickTasks(nil, reg, in, &out, args, func() { restored = true })
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		if !reflect.DeepEqual(names, []string{"clean", "deploy"}) {
+			t.Fatalf("expected [clean deploy], but got %v", names)
+		}
+		if !restored {
+			t.Fatal("expected the terminal to be restored before prompting")
+		}
+		if v, _ := args.get("deploy", "env"); v != "prod" {
+			t.Fatalf("expected env to be prod, but got %q", v)
+		}
+		if strings.Contains(out.String(), "internal") {
+			t.Fatal("expected hidden tasks not to be listed")
+		}
+		if !strings.Contains(out.String(), "  no tasks match\n") {
+			t.Fatalf("expected no tasks to match, but got:\n%s", out.String())
+		}
+		if !strings.Contains(out.String(), clearLineUp+"> [ ] deploy   ships it\ntype to filter") {
+			t.Fatalf("expected the list to be redrawn filtered, but got:\n%s", out.String())
+		}
+		if !strings.Contains(out.String(), "argument \"env\" is required") {
+			t.Fatalf("expected the empty value to be rejected, but got:\n%s", out.String())
+		}
+	})
+
+	t.Run("the highlighted task runs when none are selected", func(t *testing.T) {
+		args := globalArgs{}
+		args.set("", "token", "secret")
+
+		names, err := pickTasks(nil, reg, strings.NewReader("pub\r"), &bytes.Buffer{}, args, nil)
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if !reflect.DeepEqual(names, []string{"publish"}) {
+			t.Fatalf("expected [publish], but got %v", names)
+		}
+	})
+
+	t.Run("quit", func(t *testing.T) {
+		for _, keys := range []string{" \x03", " \x1b"} {
+			names, err := pickTasks(nil, reg, strings.NewReader(keys), &bytes.Buffer{}, globalArgs{}, nil)
+			if err != errPickerCanceled || names != nil {
+				t.Fatalf("%q: expected the picker to be canceled, but got %v, %v", keys, names, err)
+			}
+		}
+	})
+
+	t.Run("end of input", func(t *testing.T) {
+		if _, err := pickTasks(nil, reg, strings.NewReader("b"), &bytes.Buffer{}, globalArgs{}, nil); err == nil {
+			t.Fatal("expected an error when the input ends, but got none")
+		}
+	})
+}
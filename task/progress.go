@@ -0,0 +1,239 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+const (
+	// progressTailLines is the number of recent output lines shown for each running task.
+	progressTailLines = 3
+
+	clearLineUp = "\x1b[1A\x1b[2K"
+)
+
+// progressRefreshInterval is how often the status region is redrawn.
+var progressRefreshInterval = 100 * time.Millisecond
+
+func newProgressReporter(hr *humanReporter, out io.Writer, width int, secrets *internal.Masker) *progressReporter {
+	return &progressReporter{
+		humanReporter: hr,
+		out:           out,
+		width:         width,
+		secrets:       secrets,
+		completed:     make(map[string]bool),
+		planned:       make(map[string]bool),
+	}
+}
+
+// progressReporter shows a status region at the bottom of a terminal with the running tasks,
+// their recent output and the number of completed tasks. When a task finishes, the region is
+// replaced by the task's log exactly as the humanReporter would have written it.
+type progressReporter struct {
+	*humanReporter
+
+	out     io.Writer
+	width   int
+	secrets *internal.Masker
+
+	mu          sync.Mutex
+	startTime   time.Time
+	planned     map[string]bool
+	completed   map[string]bool
+	running     []*runningTask
+	regionLines int
+	stop        chan struct{}
+	stopped     chan struct{}
+}
+
+type runningTask struct {
+	task      Task
+	startTime time.Time
+	output    internal.Recorder
+	tail      []string
+	partial   string
+}
+
+func (r *progressReporter) writer() io.Writer {
+	return progressWriter{r}
+}
+
+func (r *progressReporter) runStarted(tasks []Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.startTime = time.Now()
+	for _, t := range tasks {
+		if t.Executor() != nil {
+			r.planned[t.Name()] = true
+		}
+	}
+
+	r.stop = make(chan struct{})
+	r.stopped = make(chan struct{})
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(progressRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.mu.Lock()
+				r.redraw()
+				r.mu.Unlock()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (r *progressReporter) taskStarted(t Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running = append(r.running, &runningTask{task: t, startTime: time.Now()})
+	r.redraw()
+}
+
+func (r *progressReporter) taskFinished(t Task, result *TaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rt *runningTask
+	for i := range r.running {
+		if r.running[i].task == t {
+			rt = r.running[i]
+			r.running = append(r.running[:i], r.running[i+1:]...)
+			break
+		}
+	}
+
+	r.clearRegion()
+	r.humanReporter.taskStarted(t)
+	if rt != nil {
+		_ = rt.output.ReplayTo(r.humanReporter.w)
+		_ = rt.output.Close()
+	}
+	r.humanReporter.taskFinished(t, result)
+
+	name := t.Name()
+	if cell, ok := t.(*matrixCell); ok {
+		name = cell.Task.Name()
+	}
+	if r.planned[name] {
+		r.completed[name] = true
+	}
+	r.draw()
+}
+
+func (r *progressReporter) runCompleted(elapsed time.Duration, results []*TaskResult, failedTasks []string) {
+	if r.stop != nil {
+		close(r.stop)
+		<-r.stopped
+	}
+
+	r.mu.Lock()
+	r.clearRegion()
+	r.mu.Unlock()
+
+	r.humanReporter.runCompleted(elapsed, results, failedTasks)
+}
+
+// redraw replaces the status region. The caller must hold the lock.
+func (r *progressReporter) redraw() {
+	r.clearRegion()
+	r.draw()
+}
+
+// clearRegion erases the status region. The caller must hold the lock.
+func (r *progressReporter) clearRegion() {
+	if r.regionLines > 0 {
+		_, _ = io.WriteString(r.out, strings.Repeat(clearLineUp, r.regionLines))
+		r.regionLines = 0
+	}
+}
+
+// draw writes the status region when tasks are running. The caller must hold the lock.
+func (r *progressReporter) draw() {
+	if len(r.running) == 0 {
+		return
+	}
+
+	ui := r.humanReporter.ui
+	var lines []string
+	for _, rt := range r.running {
+		lines = append(lines, fmt.Sprintf("%s    | %s %s",
+			ui.Info("RUN"),
+			ui.TaskName(rt.task.Name()),
+			ui.Duration(time.Since(rt.startTime).Round(100*time.Millisecond).String())))
+
+		tail := rt.tail
+		if rt.partial != "" {
+			tail = append(append([]string{}, tail...), rt.partial)
+		}
+		if len(tail) > progressTailLines {
+			tail = tail[len(tail)-progressTailLines:]
+		}
+		for _, line := range tail {
+			lines = append(lines, string(taskOutputPrefix)+ui.Lowlight(r.truncate(line, len(taskOutputPrefix))))
+		}
+	}
+	lines = append(lines, ui.Lowlight(fmt.Sprintf("[%d/%d] %s",
+		len(r.completed),
+		len(r.planned),
+		time.Since(r.startTime).Round(100*time.Millisecond))))
+
+	for _, line := range lines {
+		_, _ = io.WriteString(r.out, line+"\n")
+	}
+	r.regionLines = len(lines)
+}
+
+// truncate removes escape sequences from the line and shortens it so that it fits on
+// one terminal line after the indent.
+func (r *progressReporter) truncate(line string, indent int) string {
+	line = internal.ANSIEscape.ReplaceAllString(r.secrets.Mask(strings.TrimRight(line, "\r")), "")
+	if max := r.width - indent - 1; max > 0 && len(line) > max {
+		line = line[:max]
+	}
+	return line
+}
+
+// progressWriter captures the output of the running task so that its recent lines can be shown
+// in the status region and its full output logged when it finishes.
+type progressWriter struct {
+	r *progressReporter
+}
+
+func (w progressWriter) Write(p []byte) (int, error) {
+	return w.WriteTimed(p, time.Now())
+}
+
+// WriteTimed implements the internal.TimedWriter interface.
+func (w progressWriter) WriteTimed(p []byte, t time.Time) (int, error) {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+
+	if len(w.r.running) == 0 {
+		w.r.clearRegion()
+		return w.r.humanReporter.w.WriteTimed(p, t)
+	}
+
+	rt := w.r.running[len(w.r.running)-1]
+	_, _ = rt.output.WriteTimed(p, t)
+
+	lines := strings.Split(rt.partial+string(p), "\n")
+	rt.partial = lines[len(lines)-1]
+	rt.tail = append(rt.tail, lines[:len(lines)-1]...)
+	if len(rt.tail) > progressTailLines {
+		rt.tail = rt.tail[len(rt.tail)-progressTailLines:]
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,60 @@
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+func TestProgressReporter(t *testing.T) {
+	progressRefreshInterval = time.Hour
+	defer func() { progressRefreshInterval = 100 * time.Millisecond }()
+
+	reg := NewRegistry()
+	declare(reg, "compile", false)
+	reg.Declare("all").DependsOn("compile")
+	compile, _ := reg.findTask("compile")
+	all, _ := reg.findTask("all")
+
+	var out bytes.Buffer
+	hr := &humanReporter{w: internal.NewPrefixWriter(&out)}
+	rep := newProgressReporter(hr, &out, 20, nil)
+
+	rep.runStarted([]Task{compile, all})
+	rep.taskStarted(compile)
+	for i := 1; i <= 4; i++ {
+		_, _ = fmt.Fprintf(rep.writer(), "line %d of the compiler output\n", i)
+	}
+	rep.redraw()
+
+	region := out.String()
+	for _, expected := range []string{"RUN    | compile", "       | line 2 of \n", "       | line 4 of \n", "[0/1]"} {
+		if !strings.Contains(region, expected) {
+			t.Errorf("expected the status region to contain %q but got:\n%s", expected, region)
+		}
+	}
+	if strings.Contains(region, "line 1") {
+		t.Errorf("expected only the last %d lines in the status region but got:\n%s", progressTailLines, region)
+	}
+
+	out.Reset()
+	rep.taskFinished(compile, newTaskResult("compile", nil, time.Second))
+	rep.runCompleted(time.Second, nil, nil)
+
+	log := out.String()
+	if !strings.HasPrefix(log, strings.Repeat(clearLineUp, 5)) {
+		t.Errorf("expected the status region to be cleared but got:\n%q", log)
+	}
+	for _, expected := range []string{"START  | compile\n", string(taskOutputPrefix) + "line 1 of the compiler output\n", "FINISH | compile in 1s\n"} {
+		if !strings.Contains(log, expected) {
+			t.Errorf("expected the log to contain %q but got:\n%s", expected, log)
+		}
+	}
+	if len(rep.completed) != 1 {
+		t.Errorf("expected 1 completed task but got %d", len(rep.completed))
+	}
+}
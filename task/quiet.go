@@ -0,0 +1,109 @@
+package task
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+// quietReporter hides the output of quiet tasks, or of every task with -quiet, unless they fail.
+// Their output is recorded while they run and is only written once they have failed, followed
+// by the usual finish line.
+type quietReporter struct {
+	reporter
+
+	// all hides the output of every task rather than only that of tasks declared Quiet.
+	all bool
+
+	mu        sync.Mutex
+	recording *internal.Recorder
+}
+
+func (r *quietReporter) writer() io.Writer {
+	return quietWriter{r}
+}
+
+func (r *quietReporter) taskStarted(t Task) {
+	r.reporter.taskStarted(t)
+	if r.all || taskQuiet(t) {
+		r.record()
+	}
+}
+
+func (r *quietReporter) taskFinished(t Task, result *TaskResult) {
+	r.stopRecording(result.Err != nil)
+	r.reporter.taskFinished(t, result)
+}
+
+func (r *quietReporter) deferredStarted() {
+	r.reporter.deferredStarted()
+	if r.all {
+		r.record()
+	}
+}
+
+func (r *quietReporter) deferredFinished(t Task, result *TaskResult) {
+	// deferred tasks are only reported when they finish, so the recording holds the output of
+	// the deferred task which just finished
+	r.stopRecording(result.Err != nil)
+	r.reporter.deferredFinished(t, result)
+	if r.all {
+		r.record()
+	}
+}
+
+func (r *quietReporter) deferredCompleted(elapsed time.Duration) {
+	r.stopRecording(false)
+	r.reporter.deferredCompleted(elapsed)
+}
+
+// record starts recording the output rather than writing it.
+func (r *quietReporter) record() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.recording = &internal.Recorder{}
+}
+
+// stopRecording discards the recorded output after writing it when replay is set.
+func (r *quietReporter) stopRecording(replay bool) {
+	r.mu.Lock()
+	recording := r.recording
+	r.recording = nil
+	r.mu.Unlock()
+
+	if recording == nil {
+		return
+	}
+	if replay {
+		_ = recording.ReplayTo(r.reporter.writer())
+	}
+	_ = recording.Close()
+}
+
+// quietWriter records the output while a quiet task runs, and otherwise writes it through.
+type quietWriter struct {
+	r *quietReporter
+}
+
+func (w quietWriter) Write(p []byte) (int, error) {
+	return w.WriteTimed(p, time.Now())
+}
+
+// WriteTimed implements the internal.TimedWriter interface.
+func (w quietWriter) WriteTimed(p []byte, t time.Time) (int, error) {
+	w.r.mu.Lock()
+	if w.r.recording != nil {
+		defer w.r.mu.Unlock()
+		return w.r.recording.WriteTimed(p, t)
+	}
+	w.r.mu.Unlock()
+
+	out := w.r.reporter.writer()
+	if tw, ok := out.(internal.TimedWriter); ok {
+		return tw.WriteTimed(p, t)
+	}
+	return out.Write(p)
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+func TestQuietReporter(t *testing.T) {
+	reg := NewRegistry()
+	declare(reg, "generate", false).Quiet()
+	declare(reg, "compile", false)
+	generate, _ := reg.findTask("generate")
+	compile, _ := reg.findTask("compile")
+
+	run := func(rep reporter, t Task, err error) {
+		rep.taskStarted(t)
+		_, _ = fmt.Fprintf(rep.writer(), "output of %s\n", t.Name())
+		rep.taskFinished(t, newTaskResult(t.Name(), err, time.Second))
+	}
+
+	testCases := []struct {
+		all      bool
+		err      error
+		expected []string
+		hidden   []string
+	}{
+		{false, nil, []string{"START  | generate", "FINISH | generate", "output of compile"}, []string{"output of generate"}},
+		{false, fmt.Errorf("failed"), []string{"output of generate\n", "FAIL   | generate", "output of compile"}, nil},
+		{true, nil, []string{"FINISH | generate", "FINISH | compile"}, []string{"output of"}},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		rep := &quietReporter{reporter: &humanReporter{w: internal.NewPrefixWriter(&out)}, all: tc.all}
+		run(rep, generate, tc.err)
+		run(rep, compile, nil)
+
+		log := out.String()
+		for _, expected := range tc.expected {
+			if !strings.Contains(log, expected) {
+				t.Errorf("all=%v err=%v: expected the log to contain %q but got:\n%s", tc.all, tc.err, expected, log)
+			}
+		}
+		for _, hidden := range tc.hidden {
+			if strings.Contains(log, hidden) {
+				t.Errorf("all=%v err=%v: expected the log not to contain %q but got:\n%s", tc.all, tc.err, hidden, log)
+			}
+		}
+		if tc.err != nil && strings.Index(log, "output of generate") > strings.Index(log, "FAIL") {
+			t.Errorf("expected the output before the failure but got:\n%s", log)
+		}
+	}
+}
@@ -24,6 +24,23 @@ func WithNamespaceSeparator(s string) RegistryOption {
 	}
 }
 
+// WithStrict sets whether the registry is validated before running. In strict mode, Validate
+// also reports declared tasks which were never completed with Do or Aggregate, and tasks that
+// collide with auto-namespace pseudo-tasks.
+func WithStrict(v bool) RegistryOption {
+	return func(r *Registry) {
+		r.strict = v
+	}
+}
+
+// WithInteractive sets whether the tasks to run are picked interactively when none are
+// given and goke is attached to a terminal.
+func WithInteractive(v bool) RegistryOption {
+	return func(r *Registry) {
+		r.interactive = v
+	}
+}
+
 // WithShouldErrorOnUnusedArgs sets whether we should return an error when unused args are detected.
 func WithShouldErrorOnUnusedArgs(v bool) RegistryOption {
 	return func(r *Registry) {
@@ -36,6 +53,7 @@ func NewRegistry(opts ...RegistryOption) *Registry {
 	r := &Registry{
 		autoNS:      false,
 		nsSeparator: ":",
+		resources:   newResourcePool(),
 	}
 	for _, opt := range opts {
 		opt(r)
@@ -49,7 +67,25 @@ type Registry struct {
 	tree                    taskTree
 	nsSeparator             string
 	autoNS                  bool
+	interactive             bool
+	strict                  bool
 	shouldErrorOnUnusedArgs bool
+	middlewares             []Middleware
+	rules                   []*rule
+	resources               *resourcePool
+	theme                   Theme
+	history                 *runHistory
+}
+
+// Use registers middlewares which are applied around the executor of every task.
+func (r *Registry) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// executor returns the task's executor wrapped by the registry's middlewares, holding
+// the task's resources while it runs.
+func (r *Registry) executor(t Task) Executor {
+	return wrapExecutor(t, t.Executor(), append([]Middleware{requiresResources(r.resources)}, r.middlewares...))
 }
 
 // Tasks returns all the tasks and pseudo-tasks.
@@ -76,6 +112,7 @@ func (r *Registry) Tasks() []Task {
 			}
 
 			builder := build(path).DependsOn(deps...)
+			builder.task.autoNamespace = true
 			tasks = append(tasks, builder.task)
 		}
 
@@ -87,24 +124,279 @@ func (r *Registry) Tasks() []Task {
 	return tasks
 }
 
-// Register a task in the Configuration.
+// Register a task in the Configuration. Register panics if a task with the same
+// name has already been registered.
 func (r *Registry) Register(task Task) {
-	r.registerTask(&r.tree, task, strings.Split(task.Name(), r.nsSeparator))
+	if err := r.TryRegister(task); err != nil {
+		panic(err.Error())
+	}
+}
+
+// TryRegister registers a task in the Configuration, returning an error if a task
+// with the same name has already been registered. The name "matrix" is reserved, since
+// -matrix:<arg> declares a matrix axis rather than an argument of such a task.
+func (r *Registry) TryRegister(task Task) error {
+	if err := checkTaskName(task.Name()); err != nil {
+		return err
+	}
+	return r.registerTask(&r.tree, task, strings.Split(task.Name(), r.nsSeparator))
+}
+
+// checkTaskName reports names which are reserved.
+func checkTaskName(name string) error {
+	if strings.EqualFold(name, matrixNamespace) {
+		return fmt.Errorf("the task name %q is reserved for -%s:<arg>", name, matrixNamespace)
+	}
+	return nil
+}
+
+// Replace declares a task which replaces the already registered task of the same
+// name. The replaced task is returned so that it may be wrapped.
+func (r *Registry) Replace(name string) (*Builder, Task, error) {
+	node := r.findNode(name)
+	if node == nil || node.task == nil {
+		return nil, nil, fmt.Errorf("cannot replace task %q: no task with that name is registered", name)
+	}
+
+	prev := node.task
+	tb := build(name)
+	node.task = tb.task
+	return tb, prev, nil
+}
+
+// Validate checks that all the tasks refer to registered tasks and that no alias refers to
+// more than one task. In strict mode, it also reports tasks whose Builder was never completed
+// with Do or Aggregate, tasks completed with a nil executor, and tasks whose name collides with
+// an auto-namespace pseudo-task.
+func (r *Registry) Validate() error {
+	allTasksMap := taskLookup(r.Tasks())
+	known := func(name string) bool {
+		_, ok := allTasksMap[strings.ToLower(name)]
+		return ok || r.matchesRule(name)
+	}
+
+	problems := aliasConflicts(r.Tasks())
+	var check func(*taskTree)
+	check = func(tree *taskTree) {
+		if t := tree.task; t != nil {
+			for _, dep := range t.Dependencies() {
+				if !known(dep) {
+					problems = append(problems, fmt.Sprintf("task %q depends on unknown task %q", t.Name(), dep))
+				}
+			}
+			for _, deferred := range t.DeferredTasks() {
+				if !known(deferred) {
+					problems = append(problems, fmt.Sprintf("task %q defers unknown task %q", t.Name(), deferred))
+				}
+			}
+			for _, finalizer := range taskFinalizedBy(t) {
+				if !known(finalizer) {
+					problems = append(problems, fmt.Sprintf("task %q is finalized by unknown task %q", t.Name(), finalizer))
+				}
+			}
+			for _, name := range append(append([]string{}, taskMustRunAfter(t)...), taskShouldRunAfter(t)...) {
+				if !known(name) {
+					problems = append(problems, fmt.Sprintf("task %q is ordered after unknown task %q", t.Name(), name))
+				}
+			}
+
+			if r.strict {
+				if dt, ok := t.(*declaredTask); ok && !dt.autoNamespace {
+					switch {
+					case !dt.completed:
+						problems = append(problems, fmt.Sprintf("task %q was declared but never completed; call Do, or Aggregate for a task which only groups its dependencies", t.Name()))
+					case !dt.aggregate && dt.executor == nil:
+						problems = append(problems, fmt.Sprintf("task %q was completed with a nil executor", t.Name()))
+					}
+				}
+				if r.autoNS && len(tree.children) > 0 {
+					problems = append(problems, fmt.Sprintf("task %q collides with the auto-namespace task for its sub-tasks", t.Name()))
+				}
+			}
+		}
+		for _, child := range tree.children {
+			check(child)
+		}
+	}
+	check(&r.tree)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid registry:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// Merge registers all the tasks from other into this registry under their existing names.
+// No tasks are registered if any of them collide with an existing task or can't be merged.
+func (r *Registry) Merge(other *Registry) error {
+	return r.Mount("", other)
+}
+
+// Mount registers all the tasks from sub into this registry under the given namespace. Dependencies
+// and deferred tasks referring to tasks in sub are rewritten to the mounted names. The middlewares
+// of sub keep applying to its tasks, and its resource capacities and file locks are carried over.
+// No tasks are registered if any of them collide with an existing task or can't be mounted, such
+// as when a resource has a different capacity in each registry.
+func (r *Registry) Mount(namespace string, sub *Registry) error {
+	subTasks := taskLookup(sub.Tasks())
+	prefix := func(name string) string {
+		name = strings.Join(strings.Split(name, sub.nsSeparator), r.nsSeparator)
+		if namespace != "" {
+			name = namespace + r.nsSeparator + name
+		}
+		return name
+	}
+	rename := func(name string) string {
+		if t, ok := subTasks[strings.ToLower(name)]; ok {
+			return prefix(t.Name())
+		}
+		if sub.matchesRule(name) {
+			return prefix(name)
+		}
+
+		// not a task from sub, so it must refer to a task in this registry
+		return name
+	}
+	renameAll := func(names []string) []string {
+		if names == nil {
+			return nil
+		}
+		renamed := make([]string, len(names))
+		for i, name := range names {
+			renamed[i] = rename(name)
+		}
+		return renamed
+	}
+
+	mount := func(t Task) Task {
+		aliases := taskAliases(t)
+		if namespace != "" {
+			aliases = make([]string, len(taskAliases(t)))
+			for i, alias := range taskAliases(t) {
+				aliases[i] = namespace + r.nsSeparator + alias
+			}
+		}
+
+		return &mountedTask{
+			Task:           t,
+			name:           rename(t.Name()),
+			aliases:        aliases,
+			dependencies:   renameAll(t.Dependencies()),
+			deferredTasks:  renameAll(t.DeferredTasks()),
+			finalizedBy:    renameAll(taskFinalizedBy(t)),
+			mustRunAfter:   renameAll(taskMustRunAfter(t)),
+			shouldRunAfter: renameAll(taskShouldRunAfter(t)),
+			sub:            sub,
+		}
+	}
+
+	var tasks []Task
+	for _, t := range sub.registeredTasks() {
+		tasks = append(tasks, mount(t))
+	}
+
+	seen := make(map[string]struct{}, len(tasks))
+	for _, t := range tasks {
+		if _, ok := seen[t.Name()]; ok {
+			return fmt.Errorf("duplicate task registered for name %q", t.Name())
+		}
+		seen[t.Name()] = struct{}{}
+		if r.registered(t.Name()) {
+			return fmt.Errorf("cannot mount task %q: a task with that name is already registered", t.Name())
+		}
+		if err := checkTaskName(t.Name()); err != nil {
+			return fmt.Errorf("cannot mount task %q: %v", t.Name(), err)
+		}
+	}
+	for name, capacity := range sub.resources.capacities {
+		if c, ok := r.resources.capacities[name]; ok && c != capacity {
+			return fmt.Errorf("cannot mount resource %q with capacity %d: it has capacity %d", name, capacity, c)
+		}
+	}
+
+	for name, capacity := range sub.resources.capacities {
+		r.resources.capacities[name] = capacity
+	}
+	if r.resources.lockDir == "" {
+		r.resources.lockDir = sub.resources.lockDir
+	}
+
+	for _, t := range tasks {
+		if err := r.TryRegister(t); err != nil {
+			return err
+		}
+	}
+
+	for _, rl := range sub.rules {
+		r.addRule(prefix(rl.pattern), rl.description, func(name string, param string) Task {
+			subName := strings.Join(strings.Split(strings.TrimPrefix(name, prefix("")), r.nsSeparator), sub.nsSeparator)
+			t, ok := sub.findTask(subName)
+			if !ok {
+				return nil
+			}
+			return mount(t)
+		})
+	}
+
+	return nil
+}
+
+// registered indicates whether a task, not a pseudo-task, has been registered with exactly the given name.
+func (r *Registry) registered(name string) bool {
+	node := r.findNode(name)
+	return node != nil && node.task != nil
+}
+
+// findNode returns the node in the task tree with exactly the given name.
+func (r *Registry) findNode(name string) *taskTree {
+	tree := &r.tree
+	for _, part := range strings.Split(name, r.nsSeparator) {
+		var next *taskTree
+		for _, child := range tree.children {
+			if child.name == part {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		tree = next
+	}
+
+	return tree
 }
 
-func (r *Registry) registerTask(tree *taskTree, task Task, parts []string) {
+// registeredTasks returns the registered tasks without any pseudo-tasks.
+func (r *Registry) registeredTasks() []Task {
+	var collect func(*taskTree) []Task
+	collect = func(tree *taskTree) []Task {
+		var tasks []Task
+		if tree.task != nil {
+			tasks = append(tasks, tree.task)
+		}
+		for _, child := range tree.children {
+			tasks = append(tasks, collect(child)...)
+		}
+		return tasks
+	}
+
+	return collect(&r.tree)
+}
+
+func (r *Registry) registerTask(tree *taskTree, task Task, parts []string) error {
 	part := parts[0]
 	for _, child := range tree.children {
 		if child.name == part {
 			if len(parts) == 1 {
 				if child.task != nil {
-					panic(fmt.Sprintf("duplicate task registered for name %q", task.Name()))
+					return fmt.Errorf("duplicate task registered for name %q", task.Name())
 				}
 				child.task = task
-			} else {
-				r.registerTask(child, task, parts[1:])
+				return nil
 			}
-			return
+			return r.registerTask(child, task, parts[1:])
 		}
 	}
 
@@ -115,9 +407,9 @@ func (r *Registry) registerTask(tree *taskTree, task Task, parts []string) {
 
 	if len(parts) == 1 {
 		child.task = task
-	} else {
-		r.registerTask(child, task, parts[1:])
+		return nil
 	}
+	return r.registerTask(child, task, parts[1:])
 }
 
 // Declare a task to be registered.
@@ -127,11 +419,87 @@ func (r *Registry) Declare(name string) *Builder {
 	return tb
 }
 
+// findTask finds the task with the given name, generating it from a rule without registering it
+// when no such task is registered.
+func (r *Registry) findTask(name string) (Task, bool) {
+	t, ok, _ := r.resolve(taskLookup(r.Tasks()), name)
+	return t, ok
+}
+
+// isAutoNamespace indicates whether the task is a pseudo-task generated by WithAutoNamespaces.
+func (r *Registry) isAutoNamespace(t Task) bool {
+	dt, ok := t.(*declaredTask)
+	return ok && dt.autoNamespace
+}
+
 func (r *Registry) taskNamespace(t Task) string {
 	parts := strings.Split(t.Name(), r.nsSeparator)
 	return strings.Join(parts[:len(parts)-1], r.nsSeparator)
 }
 
+// mountedTask is a task from another registry renamed into this one. Optional capabilities of
+// the task are forwarded, since embedding only promotes the methods of Task.
+type mountedTask struct {
+	Task
+
+	name           string
+	aliases        []string
+	dependencies   []string
+	deferredTasks  []string
+	finalizedBy    []string
+	mustRunAfter   []string
+	shouldRunAfter []string
+
+	// sub is the registry the task was mounted from, whose middlewares apply to the task.
+	sub *Registry
+}
+
+func (t *mountedTask) Aliases() []string {
+	return t.aliases
+}
+func (t *mountedTask) Dependencies() []string {
+	return t.dependencies
+}
+func (t *mountedTask) Deprecation() *Deprecation {
+	return taskDeprecation(t.Task)
+}
+func (t *mountedTask) Executor() Executor {
+	return wrapExecutor(t, t.Task.Executor(), t.sub.middlewares)
+}
+func (t *mountedTask) FinalizedBy() []string {
+	return t.finalizedBy
+}
+func (t *mountedTask) LongDescription() string {
+	return taskLongDescription(t.Task)
+}
+func (t *mountedTask) Matrix() []MatrixAxis {
+	return taskMatrix(t.Task)
+}
+func (t *mountedTask) MustRunAfter() []string {
+	return t.mustRunAfter
+}
+func (t *mountedTask) Param() string {
+	if p, ok := t.Task.(parameterizedTask); ok {
+		return p.Param()
+	}
+	return ""
+}
+func (t *mountedTask) Name() string {
+	return t.name
+}
+func (t *mountedTask) Quiet() bool {
+	return taskQuiet(t.Task)
+}
+func (t *mountedTask) Resources() []string {
+	return taskResources(t.Task)
+}
+func (t *mountedTask) DeferredTasks() []string {
+	return t.deferredTasks
+}
+func (t *mountedTask) ShouldRunAfter() []string {
+	return t.shouldRunAfter
+}
+
 type taskTree struct {
 	name     string
 	task     Task
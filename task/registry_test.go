@@ -1,6 +1,9 @@
 package task
 
 import (
+	"bytes"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -75,6 +78,9 @@ func TestShouldExitOnUnusedArgs(t *testing.T) {
 		{[]string{"foo", "--bar", "--baz", "quux", "--fake"}, true},
 		{[]string{"foo", "--bar", "--baz", "quux", "--quux:corge"}, false},
 		{[]string{"foo", "--bar", "--baz", "quux", "--quux:fake"}, true},
+		// goke's own global options are never unused, but are when scoped to a task
+		{[]string{"foo", "-v", "-color=false", "quux"}, false},
+		{[]string{"foo", "quux", "--quux:verbose"}, true},
 	}
 
 	for _, tc := range testCases {
@@ -91,3 +97,216 @@ func TestShouldExitOnUnusedArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestMount(t *testing.T) {
+	sub := NewRegistry(WithNamespaceSeparator("/"))
+	declare(sub, "build", false).DependsOn("gen/proto", "root")
+	declare(sub, "gen/proto", false).Alias("proto")
+	declare(sub, "test", false).DependsOn("BUILD").Defer("gen/proto")
+
+	registry := NewRegistry(WithAutoNamespaces(true))
+	declare(registry, "root", false)
+	if err := registry.Mount("svc-a", sub); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	runOrder = []string{}
+	if err := Run(registry, []string{"svc-a:test"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	// executors are shared with the sub registry, so they record the original names
+	expected := []string{"gen/proto", "root", "build", "test", "gen/proto"}
+	if !reflect.DeepEqual(runOrder, expected) {
+		t.Fatalf("expected run order %v but got %v", expected, runOrder)
+	}
+
+	if _, ok := registry.findTask("svc-a:proto"); !ok {
+		t.Fatal("expected alias to be mounted under the namespace")
+	}
+
+	if err := registry.Mount("svc-a", sub); err == nil {
+		t.Fatal("expected an error when mounting colliding tasks, but got none")
+	}
+}
+
+func TestMountMiddlewaresAndResources(t *testing.T) {
+	sub := NewRegistry(WithResourceCapacity("db", 2))
+	var wrapped []string
+	sub.Use(func(t Task, next Executor) Executor {
+		return func(ctx *Context) error {
+			wrapped = append(wrapped, t.Name())
+			return next(ctx)
+		}
+	})
+	declare(sub, "migrate", false).Requires("db")
+
+	registry := NewRegistry()
+	declare(registry, "root", false)
+	if err := registry.Mount("svc", sub); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if err := Run(registry, []string{"svc:migrate", "root"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if !reflect.DeepEqual(wrapped, []string{"svc:migrate"}) {
+		t.Fatalf("expected the sub registry's middleware to wrap only its tasks, but got %v", wrapped)
+	}
+	if registry.resources.capacity("db") != 2 {
+		t.Fatalf("expected the capacity of db to be carried over, but got %d", registry.resources.capacity("db"))
+	}
+
+	// nothing is mounted when any part of the sub registry can't be
+	conflicting := NewRegistry(WithResourceCapacity("db", 1))
+	if err := conflicting.Mount("svc", sub); err == nil {
+		t.Fatal("expected an error when mounting a resource with another capacity, but got none")
+	}
+	if len(conflicting.registeredTasks()) != 0 {
+		t.Fatalf("expected no tasks to be mounted, but got %v", conflicting.registeredTasks())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	other := NewRegistry()
+	declare(other, "lint", false)
+	declare(other, "fmt", false)
+
+	registry := NewRegistry()
+	declare(registry, "build", false)
+	if err := registry.Merge(other); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if len(registry.Tasks()) != 3 {
+		t.Fatalf("expected 3 tasks, but got %d", len(registry.Tasks()))
+	}
+
+	colliding := NewRegistry()
+	declare(colliding, "test", false)
+	declare(colliding, "build", false)
+	if err := registry.Merge(colliding); err == nil {
+		t.Fatal("expected an error when merging colliding tasks, but got none")
+	}
+	if _, ok := registry.findTask("test"); ok {
+		t.Fatal("expected no tasks to be merged when a collision occurs")
+	}
+}
+
+func TestTryRegister(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.TryRegister(build("foo").task); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if err := registry.TryRegister(build("foo").task); err == nil {
+		t.Fatal("expected an error when registering a duplicate, but got none")
+	}
+	if err := registry.TryRegister(build("Matrix").task); err == nil {
+		t.Fatal("expected an error when registering the reserved name matrix, but got none")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	registry := NewRegistry()
+	declare(registry, "dep", false)
+	declare(registry, "test", false).DependsOn("dep")
+
+	b, prev, err := registry.Replace("test")
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	b.DependsOn(prev.Dependencies()...).Do(func(ctx *Context) error {
+		runOrder = append(runOrder, "before")
+		return prev.Executor()(ctx)
+	})
+
+	runOrder = []string{}
+	if err := Run(registry, []string{"test"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	expected := []string{"dep", "before", "test"}
+	if !reflect.DeepEqual(runOrder, expected) {
+		t.Fatalf("expected run order %v but got %v", expected, runOrder)
+	}
+
+	if _, _, err := registry.Replace("missing"); err == nil {
+		t.Fatal("expected an error when replacing a missing task, but got none")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("unknown references", func(t *testing.T) {
+		registry := NewRegistry()
+		declare(registry, "build", false).DependsOn("missing")
+		if err := registry.Validate(); err == nil {
+			t.Fatal("expected an error, but got none")
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		registry := NewRegistry(WithAutoNamespaces(true))
+		registry.Declare("incomplete")
+		declare(registry, "sa", false)
+		declare(registry, "sa:lint", false)
+		if err := registry.Validate(); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		registry := NewRegistry(WithAutoNamespaces(true), WithStrict(true))
+		registry.Declare("incomplete")
+		declare(registry, "sa", false)
+		declare(registry, "sa:lint", false)
+		registry.Declare("agg").DependsOn("sa:lint").Aggregate()
+		registry.Declare("deps").DependsOn("sa:lint")
+		registry.Declare("nil").Do(nil)
+
+		err := registry.Validate()
+		if err == nil {
+			t.Fatal("expected an error, but got none")
+		}
+		for _, expected := range []string{`"incomplete"`, `"sa"`, `"deps"`, `"nil"`} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("expected error to mention %s, but got %v", expected, err)
+			}
+		}
+		if strings.Contains(err.Error(), `"agg"`) {
+			t.Errorf("expected aggregate task to be valid, but got %v", err)
+		}
+
+		if err := Run(registry, []string{"sa:lint"}); err == nil {
+			t.Fatal("expected Run to fail validation in strict mode, but got no error")
+		}
+	})
+}
+
+// plainTask implements only the methods of Task, like tasks implemented outside of this package.
+type plainTask struct {
+	dummyTask
+	executor Executor
+}
+
+func (t plainTask) Executor() Executor {
+	return t.executor
+}
+
+func TestRegisterPlainTask(t *testing.T) {
+	sub := NewRegistry()
+	sub.Register(plainTask{dummyTask: "plain", executor: makeExecutor("plain", false)})
+	declare(sub, "build", false).DependsOn("plain")
+	reg := NewRegistry()
+	if err := reg.Mount("sub", sub); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	runOrder = []string{}
+	if err := Run(reg, []string{"sub:build", "-quiet"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if !reflect.DeepEqual(runOrder, []string{"plain", "build"}) {
+		t.Fatalf("expected run order [plain build] but got %v", runOrder)
+	}
+
+	var out bytes.Buffer
+	if err := printList(reg, "json", &out); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+}
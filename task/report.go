@@ -0,0 +1,382 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/craiggwilson/goke/task/event"
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+// reporter reports the progress of a run.
+type reporter interface {
+	// contextParams returns the parameters for the Context of each task.
+	contextParams() []ContextParam
+	// writer returns the writer task output is sent to.
+	writer() io.Writer
+
+	unusedArg(name string)
+	runStarted(tasks []Task)
+	deprecated(t Task, d *Deprecation)
+	taskStarted(t Task)
+	taskFinished(t Task, result *TaskResult)
+	deferredStarted()
+	deferredSkipped(t Task, err error)
+	deferredFinished(t Task, result *TaskResult)
+	deferredCompleted(elapsed time.Duration)
+	deferredFailed(err error)
+	durationRegressed(regression *durationRegression)
+	criticalPath(path []Task, durations map[string]time.Duration, total, elapsed time.Duration)
+	runCompleted(elapsed time.Duration, results []*TaskResult, failedTasks []string)
+}
+
+var taskOutputPrefix = []byte("       | ")
+
+// humanReporter reports progress in a human readable format.
+type humanReporter struct {
+	ui      *TUI
+	w       *internal.PrefixWriter
+	verbose bool
+	secrets *internal.Masker
+}
+
+func (r *humanReporter) contextParams() []ContextParam {
+	return []ContextParam{WithUI(r.ui), WithVerbose(r.verbose)}
+}
+
+func (r *humanReporter) writer() io.Writer {
+	return r.w
+}
+
+func (r *humanReporter) unusedArg(name string) {
+	_, _ = fmt.Fprintln(r.w, r.ui.Error("WARNING"), "unused argument", name)
+}
+
+func (r *humanReporter) runStarted([]Task) {}
+
+func (r *humanReporter) deprecated(t Task, d *Deprecation) {
+	_, _ = fmt.Fprintln(r.w, r.ui.Warning("WARN"), "  |", r.ui.TaskName(t.Name()), "is deprecated:", d.String())
+}
+
+func (r *humanReporter) taskStarted(t Task) {
+	_, _ = fmt.Fprintln(r.w, r.ui.Info("START"), " |", r.ui.TaskName(t.Name()))
+	r.w.SetPrefix(taskOutputPrefix)
+}
+
+func (r *humanReporter) taskFinished(t Task, result *TaskResult) {
+	r.w.SetPrefix(nil)
+	if result.Err != nil {
+		_, _ = fmt.Fprintln(r.w, r.ui.Error("FAIL"), "  |", r.ui.TaskName(t.Name()), "in", r.ui.Duration(result.Duration.String()))
+		r.w.SetPrefix(taskOutputPrefix)
+		_, _ = fmt.Fprintln(r.w, r.ui.Highlight(r.secrets.Mask(result.Err.Error())))
+		r.logStack(result.Err)
+		r.w.SetPrefix(nil)
+	} else {
+		_, _ = fmt.Fprintln(r.w, r.ui.Success("FINISH"), "|", r.ui.TaskName(t.Name()), "in", r.ui.Duration(result.Duration.String()))
+	}
+}
+
+func (r *humanReporter) deferredStarted() {
+	_, _ = fmt.Fprintln(r.w, r.ui.Info("START"), " |", r.ui.Highlight("run deferred tasks"))
+	r.w.SetPrefix(taskOutputPrefix)
+}
+
+func (r *humanReporter) deferredSkipped(t Task, err error) {
+	r.w.SetPrefix(nil)
+	_, _ = fmt.Fprintln(r.w, r.ui.Warning("WARN"), "  |", r.ui.TaskName(t.Name()), "skipped:", r.secrets.Mask(err.Error()))
+	r.w.SetPrefix(taskOutputPrefix)
+}
+
+func (r *humanReporter) deferredFinished(t Task, result *TaskResult) {
+	if result.Err != nil {
+		r.w.SetPrefix(nil)
+		_, _ = fmt.Fprintln(r.w, r.ui.Warning("WARN"), "  |", r.ui.TaskName(t.Name()), "failed:", r.secrets.Mask(result.Err.Error()))
+		r.w.SetPrefix(taskOutputPrefix)
+		r.logStack(result.Err)
+	} else {
+		_, _ = fmt.Fprintln(r.w, r.ui.TaskName(t.Name()), "finished")
+	}
+}
+
+func (r *humanReporter) deferredCompleted(elapsed time.Duration) {
+	r.w.SetPrefix(nil)
+	_, _ = fmt.Fprintln(r.w, r.ui.Success("FINISH"), "|", r.ui.Highlight("run deferred tasks"), "in", r.ui.Duration(elapsed.String()))
+}
+
+func (r *humanReporter) deferredFailed(err error) {
+	// should not happen since deferred tasks are validated when building the primary task list
+	_, _ = fmt.Fprintln(r.w, r.ui.Error("WARNING"), "Building deferred task list failed:", r.secrets.Mask(err.Error()))
+}
+
+func (r *humanReporter) durationRegressed(regression *durationRegression) {
+	_, _ = fmt.Fprintln(r.w, r.ui.Warning("WARN"), "  |", r.ui.TaskName(regression.result.Task), fmt.Sprintf(
+		"took %.1fx longer than the median of the last %d runs (%s)",
+		float64(regression.result.Duration)/float64(regression.median),
+		regression.runs,
+		regression.median.Round(time.Millisecond)))
+}
+
+func (r *humanReporter) criticalPath(path []Task, durations map[string]time.Duration, total, elapsed time.Duration) {
+	printCriticalPath(r.ui, r.w, path, durations, total, elapsed)
+}
+
+func (r *humanReporter) runCompleted(elapsed time.Duration, results []*TaskResult, failedTasks []string) {
+	_, _ = fmt.Fprintln(r.w, "---------------")
+	printSummary(r.ui, r.w, results, r.secrets)
+	if len(failedTasks) > 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintln(r.w, r.ui.Success(fmt.Sprint("Completed in ", elapsed)))
+}
+
+// logStack logs the stack trace of a panic when running verbosely.
+func (r *humanReporter) logStack(err error) {
+	if perr, ok := err.(*PanicError); ok && r.verbose {
+		_, _ = r.w.Write(r.secrets.MaskBytes(perr.Stack))
+	}
+}
+
+// jsonReporter reports progress as a stream of JSON events.
+type jsonReporter struct {
+	logger  *internal.JSONLogger
+	verbose bool
+}
+
+func (r *jsonReporter) contextParams() []ContextParam {
+	return []ContextParam{WithVerbose(r.verbose)}
+}
+
+func (r *jsonReporter) writer() io.Writer {
+	return r.logger
+}
+
+func (r *jsonReporter) unusedArg(name string) {
+	r.logger.Log(&event.Event{Type: event.UnusedArg, Arg: name})
+}
+
+func (r *jsonReporter) runStarted(tasks []Task) {
+	names := make([]string, len(tasks))
+	for i, t := range tasks {
+		names[i] = t.Name()
+	}
+	r.logger.Log(&event.Event{Type: event.RunStart, Tasks: names})
+}
+
+func (r *jsonReporter) deprecated(t Task, d *Deprecation) {
+	r.logger.Log(&event.Event{
+		Type:        event.TaskDeprecated,
+		Task:        t.Name(),
+		Message:     d.String(),
+		Replacement: d.Replacement,
+	})
+}
+
+func (r *jsonReporter) taskStarted(t Task) {
+	r.logger.Log(&event.Event{Type: event.TaskStart, Task: t.Name()})
+	r.logger.SetTask(t.Name(), false)
+}
+
+func (r *jsonReporter) taskFinished(t Task, result *TaskResult) {
+	r.logger.SetTask("", false)
+	r.logger.Log(resultEvent(t, result, false))
+}
+
+func (r *jsonReporter) deferredStarted() {
+	r.logger.Log(&event.Event{Type: event.DeferredStart})
+	r.logger.SetTask("", true)
+}
+
+func (r *jsonReporter) deferredSkipped(t Task, err error) {
+	r.logger.Log(&event.Event{
+		Type:     event.TaskSkip,
+		Task:     t.Name(),
+		Deferred: true,
+		Error:    err.Error(),
+	})
+}
+
+func (r *jsonReporter) deferredFinished(t Task, result *TaskResult) {
+	r.logger.Log(resultEvent(t, result, true))
+}
+
+func (r *jsonReporter) deferredCompleted(elapsed time.Duration) {
+	r.logger.SetTask("", false)
+	r.logger.Log(&event.Event{Type: event.DeferredFinish, DurationMs: event.Milliseconds(elapsed)})
+}
+
+func (r *jsonReporter) deferredFailed(err error) {
+	// Should not happen since deferred tasks are validated when building the primary task list.
+	r.logger.Log(&event.Event{
+		Type:    event.Warning,
+		Message: "building deferred task list failed",
+		Error:   err.Error(),
+	})
+}
+
+func (r *jsonReporter) durationRegressed(regression *durationRegression) {
+	r.logger.Log(&event.Event{
+		Type:       event.DurationRegression,
+		Task:       regression.result.Task,
+		DurationMs: event.Milliseconds(regression.result.Duration),
+		MedianMs:   event.Milliseconds(regression.median),
+		Runs:       regression.runs,
+	})
+}
+
+func (r *jsonReporter) criticalPath(path []Task, _ map[string]time.Duration, total, _ time.Duration) {
+	names := make([]string, len(path))
+	for i, t := range path {
+		names[i] = t.Name()
+	}
+	r.logger.Log(&event.Event{Type: event.CriticalPath, Tasks: names, DurationMs: event.Milliseconds(total)})
+}
+
+func (r *jsonReporter) runCompleted(elapsed time.Duration, _ []*TaskResult, failedTasks []string) {
+	status := event.StatusOK
+	if len(failedTasks) > 0 {
+		status = event.StatusFailed
+	}
+	r.logger.Log(&event.Event{
+		Type:        event.RunFinish,
+		Status:      status,
+		DurationMs:  event.Milliseconds(elapsed),
+		FailedTasks: failedTasks,
+	})
+}
+
+// resultEvent makes the task_finish event of the result, adding the stack trace for panics.
+func resultEvent(t Task, result *TaskResult, deferred bool) *event.Event {
+	e := &event.Event{
+		Type:       event.TaskFinish,
+		Task:       t.Name(),
+		Deferred:   deferred,
+		Status:     string(result.Status),
+		DurationMs: event.Milliseconds(result.Duration),
+	}
+	if result.Err != nil {
+		e.Error = result.Err.Error()
+		if perr, ok := result.Err.(*PanicError); ok {
+			e.Stack = string(perr.Stack)
+		}
+	}
+	return e
+}
+
+// multiReporter reports to each of its reporters. The tasks' Context is configured by the first.
+type multiReporter []reporter
+
+func (m multiReporter) contextParams() []ContextParam {
+	return m[0].contextParams()
+}
+
+func (m multiReporter) writer() io.Writer {
+	var tee teeWriter
+	for _, r := range m {
+		tee = append(tee, r.writer())
+	}
+	return tee
+}
+
+func (m multiReporter) unusedArg(name string) {
+	for _, r := range m {
+		r.unusedArg(name)
+	}
+}
+
+func (m multiReporter) runStarted(tasks []Task) {
+	for _, r := range m {
+		r.runStarted(tasks)
+	}
+}
+
+func (m multiReporter) deprecated(t Task, d *Deprecation) {
+	for _, r := range m {
+		r.deprecated(t, d)
+	}
+}
+
+func (m multiReporter) taskStarted(t Task) {
+	for _, r := range m {
+		r.taskStarted(t)
+	}
+}
+
+func (m multiReporter) taskFinished(t Task, result *TaskResult) {
+	for _, r := range m {
+		r.taskFinished(t, result)
+	}
+}
+
+func (m multiReporter) deferredStarted() {
+	for _, r := range m {
+		r.deferredStarted()
+	}
+}
+
+func (m multiReporter) deferredSkipped(t Task, err error) {
+	for _, r := range m {
+		r.deferredSkipped(t, err)
+	}
+}
+
+func (m multiReporter) deferredFinished(t Task, result *TaskResult) {
+	for _, r := range m {
+		r.deferredFinished(t, result)
+	}
+}
+
+func (m multiReporter) deferredCompleted(elapsed time.Duration) {
+	for _, r := range m {
+		r.deferredCompleted(elapsed)
+	}
+}
+
+func (m multiReporter) deferredFailed(err error) {
+	for _, r := range m {
+		r.deferredFailed(err)
+	}
+}
+
+func (m multiReporter) durationRegressed(regression *durationRegression) {
+	for _, r := range m {
+		r.durationRegressed(regression)
+	}
+}
+
+func (m multiReporter) criticalPath(path []Task, durations map[string]time.Duration, total, elapsed time.Duration) {
+	for _, r := range m {
+		r.criticalPath(path, durations, total, elapsed)
+	}
+}
+
+func (m multiReporter) runCompleted(elapsed time.Duration, results []*TaskResult, failedTasks []string) {
+	for _, r := range m {
+		r.runCompleted(elapsed, results, failedTasks)
+	}
+}
+
+// teeWriter writes to each of its writers, passing the time of the write along to those which
+// annotate lines with it.
+type teeWriter []io.Writer
+
+func (w teeWriter) Write(p []byte) (int, error) {
+	return w.WriteTimed(p, time.Now())
+}
+
+// WriteTimed implements the internal.TimedWriter interface.
+func (w teeWriter) WriteTimed(p []byte, t time.Time) (int, error) {
+	for _, tw := range w {
+		var err error
+		if timed, ok := tw.(internal.TimedWriter); ok {
+			_, err = timed.WriteTimed(p, t)
+		} else {
+			_, err = tw.Write(p)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
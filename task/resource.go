@@ -0,0 +1,172 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultLockDir = ".goke/locks"
+
+// lockFileNameReplacer makes resource names safe to use as file names.
+var lockFileNameReplacer = strings.NewReplacer("/", "_", "\\", "_", ":", "_")
+
+// lockPollInterval is how often a lock held by another process is retried.
+var lockPollInterval = 100 * time.Millisecond
+
+// WithResourceCapacity sets how many tasks may hold the named resource at the same time.
+// Resources which are not configured have a capacity of 1.
+func WithResourceCapacity(name string, capacity int) RegistryOption {
+	return func(r *Registry) {
+		r.resources.capacities[name] = capacity
+	}
+}
+
+// WithFileLocks sets whether resources are also locked across processes using lock files
+// in .goke/locks, so that concurrent invocations in the same workspace are serialized.
+func WithFileLocks(v bool) RegistryOption {
+	return func(r *Registry) {
+		r.resources.lockDir = ""
+		if v {
+			r.resources.lockDir = defaultLockDir
+		}
+	}
+}
+
+func newResourcePool() *resourcePool {
+	return &resourcePool{
+		capacities: make(map[string]int),
+		sems:       make(map[string]chan struct{}),
+	}
+}
+
+// resourcePool limits the number of tasks concurrently holding each resource.
+type resourcePool struct {
+	capacities map[string]int
+	lockDir    string
+
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+func (p *resourcePool) capacity(name string) int {
+	if c, ok := p.capacities[name]; ok && c > 0 {
+		return c
+	}
+	return 1
+}
+
+func (p *resourcePool) sem(name string) chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sem, ok := p.sems[name]
+	if !ok {
+		sem = make(chan struct{}, p.capacity(name))
+		p.sems[name] = sem
+	}
+	return sem
+}
+
+// acquire acquires each of the resources in a consistent order to avoid deadlocks. The
+// returned function releases them.
+func (p *resourcePool) acquire(ctx *Context, resources []string) (func(), error) {
+	names := append([]string{}, resources...)
+	sort.Strings(names)
+
+	var releases []func()
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+
+		sem := p.sem(name)
+		select {
+		case sem <- struct{}{}:
+		default:
+			ctx.Logf("waiting for resource %q\n", name)
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			}
+		}
+		releases = append(releases, func() { <-sem })
+
+		if p.lockDir != "" {
+			unlock, err := p.lockFile(ctx, name)
+			if err != nil {
+				release()
+				return nil, err
+			}
+			releases = append(releases, unlock)
+		}
+	}
+
+	return release, nil
+}
+
+// lockFile acquires one of the resource's lock files, waiting until one is available.
+func (p *resourcePool) lockFile(ctx *Context, name string) (func(), error) {
+	if err := os.MkdirAll(p.lockDir, 0777); err != nil {
+		return nil, fmt.Errorf("failed creating lock directory: %v", err)
+	}
+
+	capacity := p.capacity(name)
+	waiting := false
+	for {
+		for slot := 0; slot < capacity; slot++ {
+			path := filepath.Join(p.lockDir, lockFileNameReplacer.Replace(name))
+			if capacity > 1 {
+				path = fmt.Sprintf("%s.%d", path, slot)
+			}
+
+			unlock, ok, err := tryLockFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed locking %q: %v", path, err)
+			}
+			if ok {
+				return unlock, nil
+			}
+		}
+
+		if !waiting {
+			ctx.Logf("waiting for resource %q held by another process, locked in %s\n", name, p.lockDir)
+			waiting = true
+		}
+
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// requiresResources is a middleware which holds the task's resources while it runs.
+func requiresResources(pool *resourcePool) Middleware {
+	return func(t Task, next Executor) Executor {
+		if len(taskResources(t)) == 0 {
+			return next
+		}
+
+		return func(ctx *Context) error {
+			release, err := pool.acquire(ctx, taskResources(t))
+			if err != nil {
+				return err
+			}
+			defer release()
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package task
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequires(t *testing.T) {
+	testCases := []struct {
+		capacity      int
+		maxConcurrent int
+	}{
+		{0, 1},
+		{2, 2},
+	}
+
+	for _, tc := range testCases {
+		var opts []RegistryOption
+		if tc.capacity > 0 {
+			opts = append(opts, WithResourceCapacity("port:8080", tc.capacity))
+		}
+		reg := NewRegistry(opts...)
+
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		reg.Declare("serve").Matrix("instance", "1", "2", "3", "4").Requires("port:8080").Do(func(ctx *Context) error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+
+		if err := Run(reg, []string{"serve"}); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if maxRunning > tc.maxConcurrent {
+			t.Fatalf("capacity %d: expected at most %d concurrent tasks, but got %d", tc.capacity, tc.maxConcurrent, maxRunning)
+		}
+	}
+}
+
+func TestTryLockFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locks")
+	if err != nil {
+		t.Fatalf("failed making temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "build.exe")
+	unlock, ok, err := tryLockFile(path)
+	if err != nil || !ok {
+		t.Fatalf("expected to acquire the lock, but got %v, %v", ok, err)
+	}
+
+	if _, ok, err := tryLockFile(path); err != nil || ok {
+		t.Fatalf("expected the lock to be held, but got %v, %v", ok, err)
+	}
+
+	unlock()
+
+	unlock, ok, err = tryLockFile(path)
+	if err != nil || !ok {
+		t.Fatalf("expected to acquire the released lock, but got %v, %v", ok, err)
+	}
+	unlock()
+}
+
+func TestTryLockFileAcrossProcesses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locks")
+	if err != nil {
+		t.Fatalf("failed making temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "build.exe")
+
+	// the lock is held until the other process releases it
+	cmd, release := startLockHolder(t, path)
+	if _, ok, err := tryLockFile(path); err != nil || ok {
+		t.Fatalf("expected the lock to be held by the other process, but got %v, %v", ok, err)
+	}
+	release()
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("expected the other process to succeed, but got %v", err)
+	}
+	unlock, ok, err := tryLockFile(path)
+	if err != nil || !ok {
+		t.Fatalf("expected to acquire the released lock, but got %v, %v", ok, err)
+	}
+	unlock()
+
+	// the lock is not left held when the other process dies
+	cmd, _ = startLockHolder(t, path)
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	unlock, ok, err = tryLockFile(path)
+	if err != nil || !ok {
+		t.Fatalf("expected to acquire the lock of the killed process, but got %v, %v", ok, err)
+	}
+	unlock()
+}
+
+func TestTryCreateLockFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locks")
+	if err != nil {
+		t.Fatalf("failed making temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "build.exe")
+
+	// a lock file of a running process is held
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0666); err != nil {
+		t.Fatalf("failed writing lock file: %v", err)
+	}
+	if _, ok, err := tryCreateLockFile(path); err != nil || ok {
+		t.Fatalf("expected the lock to be held, but got %v, %v", ok, err)
+	}
+
+	// a lock file of a process which has exited is stale
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed running process: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)), 0666); err != nil {
+		t.Fatalf("failed writing lock file: %v", err)
+	}
+	unlock, ok, err := tryCreateLockFile(path)
+	if err != nil || !ok {
+		t.Fatalf("expected to replace the stale lock, but got %v, %v", ok, err)
+	}
+	if _, ok, err := tryCreateLockFile(path); err != nil || ok {
+		t.Fatalf("expected the lock to be held, but got %v, %v", ok, err)
+	}
+	unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock file to be removed, but got %v", err)
+	}
+
+	// a lock file without a pid is held until the grace period has passed
+	if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+		t.Fatalf("failed writing lock file: %v", err)
+	}
+	if _, ok, err := tryCreateLockFile(path); err != nil || ok {
+		t.Fatalf("expected the lock without a pid to be held, but got %v, %v", ok, err)
+	}
+	old := time.Now().Add(-2 * lockGracePeriod)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("failed aging lock file: %v", err)
+	}
+
+	// only one process takes over a stale lock at a time
+	if err := ioutil.WriteFile(path+".takeover", nil, 0666); err != nil {
+		t.Fatalf("failed writing takeover file: %v", err)
+	}
+	if _, ok, err := tryCreateLockFile(path); err != nil || ok {
+		t.Fatalf("expected the lock being taken over to be held, but got %v, %v", ok, err)
+	}
+	if err := os.Remove(path + ".takeover"); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, ok, err = tryCreateLockFile(path)
+	if err != nil || !ok {
+		t.Fatalf("expected to take over the lock without a pid, but got %v, %v", ok, err)
+	}
+	unlock()
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("expected no files to be left behind, but got %d", len(files))
+	}
+}
+
+// startLockHolder starts a process which takes the lock and holds it until released.
+func startLockHolder(t *testing.T, path string) (*exec.Cmd, func()) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHolderProcess$")
+	cmd.Env = append(os.Environ(), "GOKE_TEST_LOCK_FILE="+path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("failed creating stdin: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed creating stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed starting process: %v", err)
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || line != "locked\n" {
+		t.Fatalf("expected the other process to take the lock, but got %q, %v", line, err)
+	}
+	return cmd, func() { _ = stdin.Close() }
+}
+
+// TestLockHolderProcess is run by startLockHolder in another process.
+func TestLockHolderProcess(t *testing.T) {
+	path := os.Getenv("GOKE_TEST_LOCK_FILE")
+	if path == "" {
+		return
+	}
+
+	unlock, ok, err := tryLockFile(path)
+	if err != nil || !ok {
+		fmt.Fprintln(os.Stderr, "failed taking the lock:", ok, err)
+		os.Exit(1)
+	}
+	fmt.Println("locked")
+	_, _ = ioutil.ReadAll(os.Stdin)
+	unlock()
+	os.Exit(0)
+}
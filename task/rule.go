@@ -0,0 +1,142 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuleFunc completes the declaration of a task generated by a rule or by DeclareEach. The
+// param is the value the task was generated for, and is available from Context.Param.
+type RuleFunc func(b *Builder, param string)
+
+// Rule declares a family of tasks matching a pattern containing a single '*'. A task is
+// materialized on demand when a name matching the pattern is required, with the text matched
+// by the '*' as its parameter. The '*' never matches the namespace separator, so "test:*"
+// matches "test:unit" but not "test:unit:fast".
+//
+// Rather than returning a Builder for the name, fn completes one given to it. A Builder can only
+// be created by registering its task, and the registry must decide when a task is materialized.
+// The description is shown in usage for the pattern before any task has been materialized.
+func (r *Registry) Rule(pattern string, description string, fn RuleFunc) {
+	r.addRule(pattern, description, func(name string, param string) Task {
+		tb := build(name).Description(description)
+		tb.task.param = param
+		fn(tb, param)
+		return tb.task
+	})
+}
+
+// DeclareEach declares a task for each of the params, named by formatting the param with format.
+func (r *Registry) DeclareEach(params []string, format string, fn RuleFunc) {
+	for _, param := range params {
+		tb := r.Declare(fmt.Sprintf(format, param))
+		tb.task.param = param
+		fn(tb, param)
+	}
+}
+
+func (r *Registry) addRule(pattern string, description string, materialize func(name string, param string) Task) {
+	parts := strings.Split(pattern, "*")
+	if len(parts) != 2 {
+		panic(fmt.Sprintf("rule pattern %q must contain a single '*'", pattern))
+	}
+
+	r.rules = append(r.rules, &rule{
+		pattern:     pattern,
+		description: description,
+		prefix:      parts[0],
+		suffix:      parts[1],
+		separator:   r.nsSeparator,
+		materialize: materialize,
+	})
+}
+
+type rule struct {
+	pattern     string
+	description string
+	prefix      string
+	suffix      string
+	separator   string
+	materialize func(name string, param string) Task
+}
+
+// match returns the canonical task name and parameter when the name matches the rule.
+func (r *rule) match(name string) (string, string, bool) {
+	lower := strings.ToLower(name)
+	if len(name) <= len(r.prefix)+len(r.suffix) ||
+		!strings.HasPrefix(lower, strings.ToLower(r.prefix)) ||
+		!strings.HasSuffix(lower, strings.ToLower(r.suffix)) {
+		return "", "", false
+	}
+
+	param := name[len(r.prefix) : len(name)-len(r.suffix)]
+	if r.separator != "" && strings.Contains(param, r.separator) {
+		return "", "", false
+	}
+	return r.prefix + param + r.suffix, param, true
+}
+
+// matchesRule indicates whether a task with the given name can be materialized by a rule.
+func (r *Registry) matchesRule(name string) bool {
+	for _, rl := range r.rules {
+		if _, _, ok := rl.match(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve finds the task with the given name in the lookup of the registered tasks. Otherwise,
+// the task is generated from a matching rule, without registering it. It reports whether the
+// task was generated.
+func (r *Registry) resolve(allTasksMap map[string]Task, name string) (Task, bool, bool) {
+	if t, ok := allTasksMap[strings.ToLower(name)]; ok {
+		return t, true, false
+	}
+
+	for _, rl := range r.rules {
+		if taskName, param, ok := rl.match(name); ok {
+			t := rl.materialize(taskName, param)
+			return t, t != nil, t != nil
+		}
+	}
+
+	return nil, false, false
+}
+
+// materialize registers the tasks with the given names, and all the tasks they refer to, which
+// are generated from rules.
+func (r *Registry) materialize(names []string) {
+	if len(r.rules) == 0 {
+		return
+	}
+
+	allTasksMap := taskLookup(r.Tasks())
+	seen := make(map[string]bool)
+	for len(names) > 0 {
+		name := names[0]
+		names = names[1:]
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		t, ok, generated := r.resolve(allTasksMap, name)
+		if generated {
+			if err := r.TryRegister(t); err != nil {
+				continue
+			}
+			allTasksMap[strings.ToLower(t.Name())] = t
+		}
+		if ok {
+			names = append(names, t.Dependencies()...)
+			names = append(names, t.DeferredTasks()...)
+			names = append(names, taskFinalizedBy(t)...)
+		}
+	}
+}
+
+// parameterizedTask is implemented by tasks generated by a rule or by DeclareEach.
+type parameterizedTask interface {
+	Param() string
+}
@@ -0,0 +1,107 @@
+package task
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRule(t *testing.T) {
+	reg := NewRegistry(WithAutoNamespaces(true))
+	var params []string
+	reg.Rule("test:*", "runs the tests for a package", func(b *Builder, param string) {
+		b.DependsOn("compile:" + param).Do(func(ctx *Context) error {
+			params = append(params, ctx.Param())
+			runOrder = append(runOrder, "test:"+ctx.Param())
+			return nil
+		})
+	})
+	reg.Rule("compile:*", "compiles a package", func(b *Builder, param string) {
+		b.Do(makeExecutor("compile:"+param, false))
+	})
+	declare(reg, "ci", false).DependsOn("test:unit")
+
+	// looking up a task generated by a rule doesn't register it
+	if _, ok := reg.findTask("test:lookup"); !ok {
+		t.Fatal("expected test:lookup to be generated by the rule")
+	}
+	if reg.registered("test:lookup") {
+		t.Fatal("expected looking up test:lookup not to register it")
+	}
+	if _, ok := reg.findTask("test:unit:fast"); ok {
+		t.Fatal("expected '*' not to match the namespace separator")
+	}
+
+	runOrder = []string{}
+	params = nil
+	if err := Run(reg, []string{"ci", "TEST:Integration"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	expected := []string{"compile:Integration", "compile:unit", "test:Integration", "test:unit", "ci"}
+	if !reflect.DeepEqual(runOrder, expected) {
+		t.Fatalf("expected run order %v but got %v", expected, runOrder)
+	}
+	if !reflect.DeepEqual(params, []string{"Integration", "unit"}) {
+		t.Fatalf("expected params [Integration unit], but got %v", params)
+	}
+
+	task, ok := reg.findTask("test")
+	if !ok || !reg.isAutoNamespace(task) {
+		t.Fatal("expected materialized tasks to participate in auto-namespaces")
+	}
+	if !reflect.DeepEqual(task.Dependencies(), []string{"test:Integration", "test:unit"}) {
+		t.Fatalf("expected the namespace task to depend on materialized tasks, but got %v", task.Dependencies())
+	}
+	if task, _ := reg.findTask("test:unit"); task.Description() != "runs the tests for a package" {
+		t.Fatalf("expected the rule description, but got %q", task.Description())
+	}
+
+	if err := Run(reg, []string{"test:"}); err == nil {
+		t.Fatal("expected an error for a name with an empty parameter")
+	}
+}
+
+func TestDeclareEach(t *testing.T) {
+	reg := NewRegistry(WithAutoNamespaces(true))
+	reg.DeclareEach([]string{"linux", "darwin", "windows"}, "dist:%s", func(b *Builder, param string) {
+		b.Do(func(ctx *Context) error {
+			runOrder = append(runOrder, "dist:"+ctx.Param())
+			return nil
+		})
+	})
+
+	runOrder = []string{}
+	if err := Run(reg, []string{"dist"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	// the namespace task depends on its sub-tasks in declaration order
+	expected := []string{"dist:linux", "dist:darwin", "dist:windows"}
+	if !reflect.DeepEqual(runOrder, expected) {
+		t.Fatalf("expected run order %v but got %v", expected, runOrder)
+	}
+}
+
+func TestMountRule(t *testing.T) {
+	sub := NewRegistry()
+	sub.Rule("dist:*", "", func(b *Builder, param string) {
+		b.DependsOn("build").Do(makeExecutor("dist:"+param, false))
+	})
+	declare(sub, "build", false)
+	declare(sub, "all", false).DependsOn("dist:linux")
+
+	reg := NewRegistry()
+	if err := reg.Mount("svc", sub); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	runOrder = []string{}
+	if err := Run(reg, []string{"svc:all", "svc:dist:darwin"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	expected := []string{"build", "dist:darwin", "dist:linux", "all"}
+	if !reflect.DeepEqual(runOrder, expected) {
+		t.Fatalf("expected run order %v but got %v", expected, runOrder)
+	}
+}
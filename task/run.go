@@ -1,7 +1,6 @@
 package task
 
 import (
-	"context"
 	"flag"
 	"fmt"
 	"io"
@@ -10,282 +9,188 @@ import (
 	"sync"
 	"time"
 
-	"github.com/mattn/go-isatty"
-
+	"github.com/craiggwilson/goke/task/event"
 	"github.com/craiggwilson/goke/task/internal"
 )
 
 const trueString = "true"
 
-// Run orders the tasks be dependencies to build an execution plan and then executes each required task.
-func Run(registry *Registry, arguments []string) error {
-	opts, err := parseArgs(arguments)
-	if err != nil {
-		return err
-	}
-
-	if _, ok := opts.args.get("", "json"); ok {
-		return runWithJSONOutput(registry, opts)
-	}
+// The values of the -timestamps option.
+const (
+	timestampsAbsolute = "abs"
+	timestampsRelative = "rel"
+)
 
-	return runWithHumanOutput(registry, opts)
+// builtinOptions are the global options used by goke itself rather than by tasks.
+var builtinOptions = map[string]bool{
+	"ci":                  true,
+	"ci-summary":          true,
+	"color":               true,
+	"critical-path":       true,
+	"help":                true,
+	"interactive":         true,
+	"json":                true,
+	"json-out":            true,
+	"json-out-color":      true,
+	"list":                true,
+	"log-file":            true,
+	"log-file-color":      true,
+	"log-file-timestamps": true,
+	"history":             true,
+	"progress":            true,
+	"quiet":               true,
+	"timestamps":          true,
+	"verbose":             true,
 }
 
-func runWithJSONOutput(registry *Registry, opts *runOptions) error {
-	tasksToRun, err := sortTasksToRun(registry.Tasks(), opts.taskNames)
+// Run orders the tasks be dependencies to build an execution plan and then executes each required task.
+func Run(registry *Registry, arguments []string) error {
+	opts, err := parseArgs(arguments)
 	if err != nil {
 		return err
 	}
-	logger := internal.NewJSONLogger(&syncWriter{Writer: os.Stdout})
 
-	if len(tasksToRun) == 0 {
-		logger.Logln("no tasks to run", map[string]string{
-			"level": "WARNING",
-		})
-		return nil
-	}
-
-	totalStartTime := time.Now()
-
-	unusedArgs := getUnusedArgs(tasksToRun, opts.args)
-	if len(unusedArgs) > 0 {
-		for _, unusedArg := range unusedArgs {
-			logger.Logln("unused arguments", map[string]string{
-				"level":     "WARNING",
-				"unusedArg": unusedArg,
-			})
-		}
-		if registry.shouldErrorOnUnusedArgs {
-			return fmt.Errorf("unused args")
+	if registry.strict {
+		if err := registry.Validate(); err != nil {
+			return err
 		}
 	}
 
-	var failedTasks []string
-	var deferredTaskNames []string
-	for _, t := range tasksToRun {
-		executor := t.Executor()
-		if executor == nil {
-			// this task is just an aggregate task
-			deferredTaskNames = append(t.DeferredTasks(), deferredTaskNames...)
-			continue
+	if len(opts.taskNames) > 0 && strings.EqualFold(opts.taskNames[0], "help") {
+		if _, ok := registry.findTask(opts.taskNames[0]); !ok {
+			opts.help = true
+			opts.taskNames = opts.taskNames[1:]
 		}
+	}
 
-		taskArgs, err := argsForTask(t, opts.args)
+	if id, ok := opts.args.get("", "history"); ok {
+		ui, err := newRunTUI(registry, opts)
 		if err != nil {
 			return err
 		}
-
-		deferredTaskNames = append(t.DeferredTasks(), deferredTaskNames...)
-
-		startTime := time.Now()
-
-		logger.Logln("starting task", map[string]string{
-			"startTime": startTime.UTC().String(),
-			"task":      t.Name(),
-		})
-
-		ctx := NewContext(context.Background(), logger, taskArgs, WithVerbose(opts.verbose))
-
-		err = executor(ctx)
-		finishedTime := time.Now()
-
-		if err != nil {
-			failedTasks = append(failedTasks, t.Name())
-			logger.Logln("finished task", map[string]string{
-				"elapsed": finishedTime.Sub(startTime).String(),
-				"task":    t.Name(),
-				"error":   err.Error(),
-				"result":  "FAIL",
-			})
-
-			if !t.ContinueOnError() {
-				break
-			}
-		} else {
-			logger.Logln("finished task", map[string]string{
-				"elapsed": finishedTime.Sub(startTime).String(),
-				"task":    t.Name(),
-				"result":  "SUCCESS",
-			})
-		}
+		return printHistory(ui, registry.history, id, os.Stdout)
 	}
 
-	if deferredTasks, err := sortTasksToRun(registry.Tasks(), deferredTaskNames); err == nil && len(deferredTasks) > 0 {
-		logger.Logln("starting deferred task", map[string]string{})
-		startTime := time.Now()
+	registry.materialize(opts.taskNames)
 
-		for _, task := range deferredTasks {
-			if executor := task.Executor(); executor != nil {
-				taskArgs, err := argsForTask(task, opts.args)
-				if err != nil {
-					logger.Logln("failed collecting args for task", map[string]string{
-						"task":  task.Name(),
-						"error": err.Error(),
-					})
-					continue
-				}
-
-				ctx := NewContext(context.Background(), logger, taskArgs, WithVerbose(opts.verbose))
-				if err := executor(ctx); err != nil {
-					logger.Logln("finished deferred task", map[string]string{
-						"task":   task.Name(),
-						"error":  err.Error(),
-						"result": "FAIL",
-					})
-				} else {
-					logger.Logln("finished deferred task", map[string]string{
-						"task":   task.Name(),
-						"result": "SUCCESS",
-					})
-				}
-			}
-		}
-		logger.Logln("deferred tasked finished", map[string]string{
-			"elapsed": time.Since(startTime).String(),
-		})
-	} else if err != nil {
-		// Should not happen since deferred tasks are validated when building the primary task list.
-		logger.Logln("building deferred task list failed", map[string]string{
-			"error": err.Error(),
-		})
+	if format, ok := opts.args.get("", "list"); ok {
+		return printList(registry, format, os.Stdout)
 	}
 
-	if len(failedTasks) > 0 {
-		return fmt.Errorf("task(s) %s failed", failedTasks)
+	if _, ok := opts.args.get("", "json"); ok {
+		return runWithJSONOutput(registry, opts)
 	}
 
-	totalDuration := time.Since(totalStartTime)
-	logger.Logln("run complete", map[string]string{
-		"totalDuration": totalDuration.String(),
-	})
-
-	return nil
+	return runWithHumanOutput(registry, opts)
 }
 
-func runWithHumanOutput(registry *Registry, opts *runOptions) error {
-	ui := newTUI(opts.color)
-
-	if opts.help {
-		return printHelp(ui, registry)
-	}
-
-	tasksToRun, err := sortTasksToRun(registry.Tasks(), opts.taskNames)
+func runWithJSONOutput(registry *Registry, opts *runOptions) error {
+	tasksToRun, err := scheduleTasksToRun(registry.Tasks(), opts.taskNames, registry.history.estimates())
 	if err != nil {
 		return err
 	}
-
 	if len(tasksToRun) == 0 {
-		return printHelp(ui, registry)
+		newJSONLogger(&syncWriter{Writer: os.Stdout}, opts).Log(&event.Event{
+			Type:    event.Warning,
+			Message: "no tasks to run",
+		})
+		return nil
 	}
 
-	writer := internal.NewPrefixWriter(&syncWriter{Writer: os.Stdout})
-	prefix := []byte("       | ")
+	run := registry.history.start(opts)
+	logger := newJSONLogger(run.tee(&syncWriter{Writer: os.Stdout}), opts)
+	return runWithReporter(registry, opts, tasksToRun, &jsonReporter{logger: logger, verbose: opts.verbose}, run)
+}
 
-	unusedArgs := getUnusedArgs(tasksToRun, opts.args)
-	if len(unusedArgs) > 0 {
-		for _, unusedArg := range unusedArgs {
-			_, _ = fmt.Fprintln(writer, ui.Error("WARNING"), "unused argument", unusedArg)
-		}
-		if registry.shouldErrorOnUnusedArgs {
-			return fmt.Errorf("unused args")
-		}
+// runWithReporter runs the tasks, reporting to rep, to the sinks given by -json-out and -log-file,
+// and to the run history.
+func runWithReporter(registry *Registry, opts *runOptions, tasksToRun []Task, rep reporter, run *historyRun) error {
+	sinks, err := openSinks(opts, registry.theme)
+	if err != nil {
+		return err
 	}
+	defer sinks.close()
 
-	totalStartTime := time.Now()
+	return runTasks(registry, opts, tasksToRun, run.reporter(sinks.reporter(rep), opts))
+}
 
-	var failedTasks []string
-	var deferredTaskNames []string
-	for _, t := range tasksToRun {
-		executor := t.Executor()
-		if executor == nil {
-			// this task is just an aggregate task
-			deferredTaskNames = append(t.DeferredTasks(), deferredTaskNames...)
-			continue
+// newRunTUI makes the TUI for the run, preferring the user's theme from GOKE_THEME over the registry's.
+func newRunTUI(registry *Registry, opts *runOptions) (*TUI, error) {
+	theme := registry.theme
+	if path := os.Getenv("GOKE_THEME"); path != "" && opts.color {
+		var err error
+		if theme, err = LoadTheme(path); err != nil {
+			return nil, err
 		}
+	}
+	return newTUI(opts.color, theme), nil
+}
 
-		taskArgs, err := argsForTask(t, opts.args)
-		if err != nil {
-			return err
-		}
-
-		deferredTaskNames = append(t.DeferredTasks(), deferredTaskNames...)
-
-		ctx := NewContext(context.Background(), writer, taskArgs, WithUI(ui), WithVerbose(opts.verbose))
-
-		ctx.Logln(ui.Info("START"), " |", ui.Highlight(t.Name()))
-		writer.SetPrefix(prefix)
-
-		startTime := time.Now()
-		err = executor(ctx)
-		finishedTime := time.Now()
+func runWithHumanOutput(registry *Registry, opts *runOptions) error {
+	ui, err := newRunTUI(registry, opts)
+	if err != nil {
+		return err
+	}
 
-		writer.SetPrefix(nil)
-		if err != nil {
-			failedTasks = append(failedTasks, t.Name())
-			ctx.Logln(ui.Error("FAIL"), "  |", ui.Highlight(t.Name()), "in", finishedTime.Sub(startTime).String())
-			writer.SetPrefix(prefix)
-			ctx.Logln(ui.Highlight(err.Error()))
-			writer.SetPrefix(nil)
-			if !t.ContinueOnError() {
-				break
-			}
-		} else {
-			ctx.Logln(ui.Success("FINISH"), "|", ui.Highlight(t.Name()), "in", finishedTime.Sub(startTime).String())
+	if opts.help {
+		if len(opts.taskNames) > 0 {
+			return printTaskHelp(ui, registry, opts.taskNames)
 		}
+		return printHelp(ui, registry)
 	}
 
-	if deferredTasks, err := sortTasksToRun(registry.Tasks(), deferredTaskNames); err == nil && len(deferredTasks) > 0 {
-		fmt.Fprintln(writer, ui.Info("START"), " |", ui.Highlight("run deferred tasks"))
-		writer.SetPrefix(prefix)
-		startTime := time.Now()
-		for _, task := range deferredTasks {
-			if executor := task.Executor(); executor != nil {
-				taskArgs, err := argsForTask(task, opts.args)
-				if err != nil {
-					writer.SetPrefix(nil)
-					fmt.Fprintln(writer, ui.Warning("WARN"), "  |", ui.Highlight(task.Name()), "skipped:", err.Error())
-					writer.SetPrefix(prefix)
-					continue
-				}
-				ctx := NewContext(context.Background(), writer, taskArgs, WithUI(ui), WithVerbose(opts.verbose))
-				if err := executor(ctx); err != nil {
-					writer.SetPrefix(nil)
-					ctx.Logln(ui.Warning("WARN"), "  |", ui.Highlight(task.Name()), "failed:", err.Error())
-					writer.SetPrefix(prefix)
-				} else {
-					ctx.Logln(ui.Highlight(task.Name()), "finished")
-				}
+	if len(opts.taskNames) == 0 && (opts.interactive || registry.interactive) && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		// without reading keys as they are pressed, help is printed as when not interactive
+		if restore, err := makeRaw(os.Stdin); err == nil {
+			taskNames, err := pickTasks(ui, registry, os.Stdin, os.Stdout, opts.args, restore)
+			restore()
+			if err == errPickerCanceled {
+				return nil
+			}
+			if err != nil {
+				return err
 			}
+			opts.taskNames = taskNames
 		}
-		writer.SetPrefix(nil)
-		fmt.Fprintln(writer, ui.Success("FINISH"), "|", ui.Highlight("run deferred tasks"), "in", time.Since(startTime).String())
-	} else if err != nil {
-		// should not happen since deferred tasks are validated when building the primary task list
-		fmt.Fprintln(writer, ui.Error("WARNING"), "Building deferred task list failed:", err.Error())
 	}
 
-	totalDuration := time.Since(totalStartTime)
-
-	if len(failedTasks) > 0 {
-		return fmt.Errorf("task(s) %s failed", failedTasks)
+	tasksToRun, err := scheduleTasksToRun(registry.Tasks(), opts.taskNames, registry.history.estimates())
+	if err != nil {
+		return err
 	}
 
-	_, _ = fmt.Fprintln(writer, "---------------")
-	_, _ = fmt.Fprintln(writer, ui.Success(fmt.Sprint("Completed in ", totalDuration)))
+	if len(tasksToRun) == 0 {
+		return printHelp(ui, registry)
+	}
 
-	return nil
+	run := registry.history.start(opts)
+	out := &syncWriter{Writer: os.Stdout}
+	w := run.tee(out)
+	var rep reporter = &humanReporter{ui: ui, w: newPrefixWriter(w, opts), verbose: opts.verbose, secrets: opts.secrets}
+	if opts.progress {
+		rep = newProgressReporter(rep.(*humanReporter), out, terminalWidth(), opts.secrets)
+	}
+	rep = &quietReporter{reporter: rep, all: opts.quiet}
+	if opts.ci != ciNone {
+		rep = &ciReporter{reporter: rep, provider: opts.ci, w: w, secrets: opts.secrets, summaryPath: opts.stepSummary}
+	}
+	return runWithReporter(registry, opts, tasksToRun, rep, run)
 }
 
 func argsForTask(task Task, args globalArgs) (map[string]string, error) {
 	taskArgs := make(map[string]string)
 	for _, da := range task.DeclaredArgs() {
 		// first look up a specific one to the task
-		v, ok := args.get(task.Name(), da.Name)
+		v, ok := taskScopedArg(task, args, da.Name)
 		if !ok {
 			// try to find one in the global namespace
 			v, ok = args.get("", da.Name)
 		}
 
+		if !ok && da.Default != "" {
+			v, ok = da.Default, true
+		}
+
 		if da.Validator != nil {
 			if err := da.Validator(da.Name, v); err != nil {
 				return nil, fmt.Errorf("failed to validate argument %q: %v", da.Name, err)
@@ -300,8 +205,26 @@ func argsForTask(task Task, args globalArgs) (map[string]string, error) {
 	return taskArgs, nil
 }
 
+// taskScopedArg returns the value of an argument given to the task by its name or by one of its
+// aliases, preferring its name.
+func taskScopedArg(t Task, args globalArgs, name string) (string, bool) {
+	for _, ns := range taskArgNamespaces(t) {
+		if v, ok := args.get(ns, name); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// taskArgNamespaces returns the namespaces of the arguments given to the task, which are its
+// name and its aliases.
+func taskArgNamespaces(t Task) []string {
+	return append([]string{t.Name()}, taskAliases(t)...)
+}
+
 func parseArgs(arguments []string) (*runOptions, error) {
 	var requiredTaskNames []string
+	var matrix []MatrixAxis
 	args := globalArgs{}
 	for _, arg := range arguments {
 		if arg[0] == '-' || arg[0] == '/' {
@@ -309,10 +232,23 @@ func parseArgs(arguments []string) (*runOptions, error) {
 			switch argName {
 			case "h":
 				argName = "help"
+			case "i":
+				argName = "interactive"
 			case "v":
 				argName = "verbose"
 			}
 
+			if taskName == matrixNamespace {
+				values := strings.Split(value, ",")
+				for _, v := range values {
+					if v == "" {
+						return nil, fmt.Errorf("invalid matrix %q, expected comma-separated values", arg)
+					}
+				}
+				matrix = append(matrix, MatrixAxis{Arg: argName, Values: values})
+				continue
+			}
+
 			args.set(taskName, argName, value)
 		} else {
 			requiredTaskNames = append(requiredTaskNames, arg)
@@ -324,17 +260,84 @@ func parseArgs(arguments []string) (*runOptions, error) {
 	helpArg, _ := args.get("", "help")
 	help := helpArg == trueString
 
-	color := isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
-	if colorArg, ok := args.get("", "color"); ok && colorArg != trueString {
-		color = false
+	interactiveArg, _ := args.get("", "interactive")
+	interactive := interactiveArg == trueString
+
+	colorArg, hasColorArg := args.get("", "color")
+	color := useColors(colorArg, hasColorArg, isTerminal(os.Stdout))
+
+	criticalPathArg, _ := args.get("", "critical-path")
+	criticalPath := criticalPathArg == trueString
+
+	progress := color && isTerminal(os.Stdout)
+	if progressArg, ok := args.get("", "progress"); ok && progressArg != trueString {
+		progress = false
+	}
+
+	ciArg, hasCIArg := args.get("", "ci")
+	ci, err := detectCI(ciArg, hasCIArg)
+	if err != nil {
+		return nil, err
+	}
+
+	// the step summary can be turned off, such as by tests which run tasks on GitHub Actions
+	var stepSummary string
+	if ciSummaryArg, ok := args.get("", "ci-summary"); ci == ciGitHub && (!ok || ciSummaryArg == trueString) {
+		stepSummary = os.Getenv("GITHUB_STEP_SUMMARY")
+	}
+
+	quietArg, _ := args.get("", "quiet")
+	quiet := quietArg == trueString
+
+	timestamps, err := parseTimestamps(args, "timestamps")
+	if err != nil {
+		return nil, err
+	}
+
+	jsonOut, _ := args.get("", "json-out")
+	logFile, _ := args.get("", "log-file")
+	if jsonOut == trueString || logFile == trueString {
+		return nil, fmt.Errorf("-json-out and -log-file require a path")
+	}
+
+	jsonOutColorArg, _ := args.get("", "json-out-color")
+	jsonOutColor := jsonOutColorArg == trueString
+
+	logFileColorArg, _ := args.get("", "log-file-color")
+	logFileColor := logFileColorArg == trueString
+
+	logFileTimestamps := timestamps
+	if _, ok := args.get("", "log-file-timestamps"); ok {
+		if logFileTimestamps, err = parseTimestamps(args, "log-file-timestamps"); err != nil {
+			return nil, err
+		}
 	}
 
+	startTime := time.Now()
 	return &runOptions{
-		args:      args,
-		verbose:   verbose,
-		help:      help,
-		color:     color,
-		taskNames: requiredTaskNames,
+		runID:        fmt.Sprint(startTime.UnixNano()),
+		secrets:      newSecrets(),
+		startTime:    startTime,
+		arguments:    arguments,
+		args:         args,
+		verbose:      verbose,
+		help:         help,
+		interactive:  interactive,
+		color:        color,
+		criticalPath: criticalPath,
+		ci:           ci,
+		stepSummary:  stepSummary,
+		progress:     progress,
+		quiet:        quiet,
+		timestamps:   timestamps,
+		jsonOut:      jsonOut,
+		logFile:      logFile,
+
+		jsonOutColor:      jsonOutColor,
+		logFileColor:      logFileColor,
+		logFileTimestamps: logFileTimestamps,
+		taskNames:         requiredTaskNames,
+		matrix:            matrix,
 	}, nil
 }
 
@@ -344,15 +347,20 @@ func getUnusedArgs(tasks []Task, args globalArgs) []string {
 	for ns, nsArgs := range args {
 		used[ns] = make(map[string]bool, len(nsArgs))
 		for arg := range nsArgs {
-			used[ns][arg] = false
+			used[ns][arg] = ns == "" && builtinOptions[arg]
 		}
 	}
 
 	for _, task := range tasks {
 		for _, da := range task.DeclaredArgs() {
-			if _, ok := args.get(task.Name(), da.Name); ok {
-				used[task.Name()][da.Name] = true
-			} else if _, ok = args.get("", da.Name); ok {
+			scoped := false
+			for _, ns := range taskArgNamespaces(task) {
+				if _, ok := args.get(ns, da.Name); ok {
+					used[ns][da.Name] = true
+					scoped = true
+				}
+			}
+			if _, ok := args.get("", da.Name); ok && !scoped {
 				used[""][da.Name] = true
 			}
 		}
@@ -375,6 +383,19 @@ func getUnusedArgs(tasks []Task, args globalArgs) []string {
 	return unusedArgs
 }
 
+// parseTimestamps parses a -timestamps option, where true means absolute timestamps.
+func parseTimestamps(args globalArgs, name string) (string, error) {
+	timestamps, _ := args.get("", name)
+	switch timestamps {
+	case "", timestampsAbsolute, timestampsRelative:
+		return timestamps, nil
+	case trueString:
+		return timestampsAbsolute, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q, expected %q or %q", name, timestamps, timestampsAbsolute, timestampsRelative)
+	}
+}
+
 func parseArg(arg string) (string, string, string) {
 	arg = strings.TrimLeftFunc(arg, func(r rune) bool {
 		return r == '-' || r == '/'
@@ -399,17 +420,72 @@ func parseArgName(name string) (string, string) {
 
 func printHelp(ui *TUI, registry *Registry) error {
 	fs := flag.NewFlagSet("goke", flag.ContinueOnError)
+	_ = fs.String("ci", "", "emit groups and annotations for github or gitlab, detected from GITHUB_ACTIONS and GITLAB_CI unless false")
+	_ = fs.Bool("ci-summary", true, "append a summary of the run to GITHUB_STEP_SUMMARY on GitHub Actions")
+	_ = fs.Bool("color", true, "color the output, which also honors NO_COLOR, FORCE_COLOR and CLICOLOR")
+	_ = fs.Bool("critical-path", false, "report the chain of dependencies which determined the duration of the run, estimating tasks which didn't run from previous runs")
+	_ = fs.Bool("h", false, "show help, or detailed help for the given tasks")
+	_ = fs.Bool("i", false, "interactively pick the tasks to run when none are given")
 	_ = fs.Bool("v", false, "generate verbose logs")
+	_ = fs.String("matrix:<arg>", "", "run the tasks declaring the arg once for each of the comma-separated values")
+	_ = fs.String("list", "", "list the tasks one per line, or as JSON with -list=json")
+	_ = fs.String("timestamps", "", "prefix every line of output with the wall-clock time (abs) or the time since the run started (rel)")
+	_ = fs.String("json-out", "", "also write the JSON event stream to the file")
+	_ = fs.String("log-file", "", "also write uncolored output to the file")
+	_ = fs.Bool("log-file-color", false, "color the output written to the -log-file")
+	_ = fs.String("log-file-timestamps", "", "the -timestamps of the output written to the -log-file, which defaults to -timestamps")
+	_ = fs.Bool("json-out-color", false, "keep the colors of task output in the -json-out events")
+	_ = fs.String("history", "", "list the recorded runs, or replay the output of the run with the given id or 'last'")
+	_ = fs.Bool("progress", true, "show the running tasks and their latest output when writing to a terminal")
+	_ = fs.Bool("quiet", false, "only show the output of tasks which fail")
 	usage(ui, fs, registry)
 	return flag.ErrHelp
 }
 
+func printTaskHelp(ui *TUI, registry *Registry, taskNames []string) error {
+	width := terminalWidth()
+	for i, name := range taskNames {
+		t, ok := registry.findTask(name)
+		if !ok {
+			return fmt.Errorf("unknown task '%s'", name)
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		taskUsage(ui, registry, t, width, os.Stdout)
+	}
+	return flag.ErrHelp
+}
+
 type runOptions struct {
-	args      globalArgs
-	verbose   bool
-	help      bool
-	color     bool
-	taskNames []string
+	// runID identifies the run in JSON events. Nano timestamps are a decent unique identifier
+	// that does not require any external dependencies.
+	runID        string
+	startTime    time.Time
+	secrets      *internal.Masker
+	arguments    []string
+	args         globalArgs
+	verbose      bool
+	help         bool
+	interactive  bool
+	color        bool
+	criticalPath bool
+	ci           ciProvider
+	stepSummary  string
+	progress     bool
+	quiet        bool
+	timestamps   string
+	jsonOut      string
+	logFile      string
+	taskNames    []string
+	matrix       []MatrixAxis
+
+	// jsonOutColor keeps the colors of task output in the -json-out events.
+	jsonOutColor bool
+	// logFileColor colors the -log-file output.
+	logFileColor bool
+	// logFileTimestamps is the -timestamps setting of the -log-file output.
+	logFileTimestamps string
 }
 
 type globalArgs map[string]map[string]string
@@ -423,6 +499,16 @@ func (ga globalArgs) get(taskName, argName string) (string, bool) {
 	return "", false
 }
 
+func (ga globalArgs) clone() globalArgs {
+	cpy := make(globalArgs, len(ga))
+	for taskName, ta := range ga {
+		for argName, value := range ta {
+			cpy.set(taskName, argName, value)
+		}
+	}
+	return cpy
+}
+
 func (ga globalArgs) set(taskName, argName, value string) {
 	ta, ok := ga[taskName]
 	if !ok {
@@ -2,10 +2,17 @@ package task
 
 import (
 	"fmt"
+	"os"
 	"reflect"
 	"testing"
 )
 
+func TestMain(m *testing.M) {
+	// tests run on GitHub Actions must not write to the summary of the job
+	os.Unsetenv("GITHUB_STEP_SUMMARY")
+	os.Exit(m.Run())
+}
+
 var runOrder []string
 
 func makeExecutor(name string, shouldError bool) Executor {
@@ -111,3 +118,235 @@ func TestDefer(t *testing.T) {
 		})
 	})
 }
+
+func TestAlias(t *testing.T) {
+	reg := NewRegistry()
+	declare(reg, "compile", false).Alias("build", "make")
+	declare(reg, "package", false).DependsOn("BUILD")
+	declare(reg, "old", false).Deprecated("old is going away", "compile")
+
+	testCases := []struct {
+		args             []string
+		expectedRunOrder []string
+	}{
+		{[]string{"build"}, []string{"compile"}},
+		{[]string{"Make"}, []string{"compile"}},
+		{[]string{"package"}, []string{"compile", "package"}},
+		{[]string{"compile"}, []string{"compile"}},
+		{[]string{"old"}, []string{"old"}},
+	}
+
+	for _, tc := range testCases {
+		runOrder = []string{}
+		if err := Run(reg, tc.args); err != nil {
+			t.Fatalf("%v: expected no error, but got %v", tc.args, err)
+		}
+		if !reflect.DeepEqual(runOrder, tc.expectedRunOrder) {
+			t.Fatalf("%v: expected run order %v but got %v", tc.args, tc.expectedRunOrder, runOrder)
+		}
+	}
+
+	// arguments may be scoped to the task by one of its aliases
+	var target string
+	scoped := NewRegistry(WithShouldErrorOnUnusedArgs(true))
+	scoped.Declare("compile").Alias("build").OptionalArg("target").Do(func(ctx *Context) error {
+		target = ctx.Get("target")
+		return nil
+	})
+	if err := Run(scoped, []string{"build", "-build:target=linux"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if target != "linux" {
+		t.Fatalf("expected the target to be linux, but got %q", target)
+	}
+
+	// an alias must not refer to more than one task
+	for _, alias := range []string{"COMPILE", "make"} {
+		conflicting := NewRegistry()
+		declare(conflicting, "compile", false).Alias("build", "make")
+		declare(conflicting, "other", false).Alias(alias)
+		if err := conflicting.Validate(); err == nil {
+			t.Fatalf("%s: expected Validate to report the conflicting alias", alias)
+		}
+		if err := Run(conflicting, []string{"compile"}); err == nil {
+			t.Fatalf("%s: expected running with a conflicting alias to fail", alias)
+		}
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	reg := NewRegistry()
+	declare(reg, "cleanup", false)
+	declare(reg, "after", false)
+	reg.Declare("boom").Defer("cleanup").Do(func(ctx *Context) error {
+		runOrder = append(runOrder, "boom")
+		panic("something went wrong")
+	})
+	reg.Declare("tolerated").ContinueOnError().Do(func(ctx *Context) error {
+		runOrder = append(runOrder, "tolerated")
+		var m map[string]string
+		m["nil"] = "map"
+		return nil
+	})
+	declare(reg, "fails", false).DependsOn("boom")
+	declare(reg, "continues", false).DependsOn("tolerated", "after")
+
+	for _, output := range [][]string{nil, {"-json"}} {
+		testCases := []struct {
+			task             string
+			expectedRunOrder []string
+		}{
+			{"fails", []string{"boom", "cleanup"}},
+			{"continues", []string{"tolerated", "after", "continues"}},
+		}
+
+		for _, tc := range testCases {
+			runOrder = []string{}
+			err := Run(reg, append([]string{tc.task}, output...))
+			if err == nil {
+				t.Fatalf("%s %v: expected an error, but got none", tc.task, output)
+			}
+			if !reflect.DeepEqual(runOrder, tc.expectedRunOrder) {
+				t.Fatalf("%s %v: expected run order %v but got %v", tc.task, output, tc.expectedRunOrder, runOrder)
+			}
+		}
+	}
+}
+
+func TestFinalizedBy(t *testing.T) {
+	reg := NewRegistry()
+	var finalizedResults []*TaskResult
+	reg.Declare("teardown").DependsOn("stopdb").Do(func(ctx *Context) error {
+		runOrder = append(runOrder, "teardown")
+		finalizedResults = append(finalizedResults, ctx.FinalizedResult())
+		return nil
+	})
+	declare(reg, "setupdb", false)
+	declare(reg, "stopdb", false)
+	declare(reg, "integration", false).DependsOn("setupdb").FinalizedBy("teardown")
+	declare(reg, "integrationErr", true).DependsOn("setupdb").FinalizedBy("teardown")
+	declare(reg, "unit", false).FinalizedBy("teardown")
+	declare(reg, "package", false).DependsOn("integration")
+	declare(reg, "packageErr", false).DependsOn("integrationErr")
+	declare(reg, "all", false).DependsOn("unit", "integration")
+
+	testCases := []struct {
+		task             string
+		shouldFailRun    bool
+		expectedRunOrder []string
+		expectedResults  []string
+	}{
+		{"package", false, []string{"setupdb", "integration", "stopdb", "teardown", "package"}, []string{"integration"}},
+		{"packageErr", true, []string{"setupdb", "integrationErr", "stopdb", "teardown"}, []string{"integrationErr"}},
+		// a shared finalizer runs once, after the last task it finalizes
+		{"all", false, []string{"unit", "setupdb", "integration", "stopdb", "teardown", "all"}, []string{"integration"}},
+	}
+
+	for _, tc := range testCases {
+		runOrder = []string{}
+		finalizedResults = nil
+		err := Run(reg, []string{tc.task})
+		if err == nil && tc.shouldFailRun {
+			t.Fatalf("%s: expected error", tc.task)
+		} else if err != nil && !tc.shouldFailRun {
+			t.Fatalf("%s: expected no error, but got %v", tc.task, err)
+		}
+		if !reflect.DeepEqual(runOrder, tc.expectedRunOrder) {
+			t.Fatalf("%s: expected run order %v but got %v", tc.task, tc.expectedRunOrder, runOrder)
+		}
+
+		var results []string
+		for _, result := range finalizedResults {
+			results = append(results, result.Task)
+			if (result.Err != nil) != tc.shouldFailRun {
+				t.Fatalf("%s: unexpected finalized error %v", tc.task, result.Err)
+			}
+		}
+		if !reflect.DeepEqual(results, tc.expectedResults) {
+			t.Fatalf("%s: expected finalized results %v but got %v", tc.task, tc.expectedResults, results)
+		}
+	}
+
+	// a finalizer given on the command line still runs after the task it finalizes
+	runOrder = []string{}
+	finalizedResults = nil
+	if err := Run(reg, []string{"teardown", "integration"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if len(finalizedResults) != 1 || finalizedResults[0] == nil || finalizedResults[0].Task != "integration" {
+		t.Fatalf("expected teardown to finalize integration, but got %v", finalizedResults)
+	}
+	if runOrder[len(runOrder)-1] != "teardown" {
+		t.Fatalf("expected teardown to run last, but got %v", runOrder)
+	}
+
+	// a task which fails before running, such as on a missing argument, is still finalized
+	declare(reg, "needsArg", false).RequiredArg("db").FinalizedBy("teardown")
+	runOrder = []string{}
+	finalizedResults = nil
+	if err := Run(reg, []string{"needsArg"}); err == nil {
+		t.Fatal("expected an error for the missing argument")
+	}
+	if !reflect.DeepEqual(runOrder, []string{"stopdb", "teardown"}) {
+		t.Fatalf("expected run order [stopdb teardown] but got %v", runOrder)
+	}
+	if len(finalizedResults) != 1 || finalizedResults[0].Err == nil {
+		t.Fatalf("expected teardown to receive the failed result, but got %v", finalizedResults)
+	}
+
+	if err := Run(reg, []string{"unknownFinalizer"}); err == nil {
+		t.Fatal("expected an error for an unknown task")
+	}
+	declare(reg, "badFinalizer", false).FinalizedBy("doesNotExist")
+	if err := Run(reg, []string{"badFinalizer"}); err == nil {
+		t.Fatal("expected an error for an unknown finalizer")
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	reg := NewRegistry()
+	reg.Declare("version:compute").Do(func(ctx *Context) error {
+		ctx.SetOutput("version", "1.2.3")
+		ctx.SetOutput("commits", 4)
+		return nil
+	})
+	reg.Declare("build").DependsOn("version:compute").Do(func(ctx *Context) error {
+		return nil
+	})
+
+	var version string
+	var outputErr error
+	reg.Declare("package").DependsOn("build").Do(func(ctx *Context) error {
+		version, outputErr = ctx.OutputString("VERSION:compute", "version")
+		return outputErr
+	})
+	reg.Declare("wrongType").DependsOn("version:compute").Do(func(ctx *Context) error {
+		_, outputErr = ctx.OutputString("version:compute", "commits")
+		return nil
+	})
+	reg.Declare("missingKey").DependsOn("version:compute").Do(func(ctx *Context) error {
+		_, outputErr = ctx.Output("version:compute", "sha")
+		return nil
+	})
+	reg.Declare("undeclared").MustRunAfter("version:compute").Do(func(ctx *Context) error {
+		_, outputErr = ctx.Output("version:compute", "version")
+		return nil
+	})
+
+	if err := Run(reg, []string{"package"}); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if version != "1.2.3" {
+		t.Fatalf("expected version 1.2.3, but got %q", version)
+	}
+
+	for _, taskNames := range [][]string{{"wrongType"}, {"missingKey"}, {"version:compute", "undeclared"}} {
+		outputErr = nil
+		if err := Run(reg, taskNames); err != nil {
+			t.Fatalf("%v: expected no error, but got %v", taskNames, err)
+		}
+		if outputErr == nil {
+			t.Fatalf("%v: expected an error reading the output, but got none", taskNames)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TaskStatus describes what happened to a task during a run.
+type TaskStatus string
+
+// The statuses of a task.
+const (
+	StatusOK      TaskStatus = "ok"
+	StatusFailed  TaskStatus = "failed"
+	StatusSkipped TaskStatus = "skipped"
+	StatusNotRun  TaskStatus = "not run"
+)
+
+// TaskResult is the outcome of running a task.
+type TaskResult struct {
+	Task     string
+	Status   TaskStatus
+	Err      error
+	Duration time.Duration
+}
+
+func newTaskResult(name string, err error, duration time.Duration) *TaskResult {
+	status := StatusOK
+	if err != nil {
+		status = StatusFailed
+	}
+	return &TaskResult{
+		Task:     name,
+		Status:   status,
+		Err:      err,
+		Duration: duration,
+	}
+}
+
+// runTasks executes the tasks in order, followed by any deferred tasks, reporting progress to the reporter.
+func runTasks(registry *Registry, opts *runOptions, tasksToRun []Task, rep reporter) error {
+	registerSecretArgs(registry.Tasks(), opts)
+
+	unusedArgs := getUnusedArgs(tasksToRun, opts.args)
+	for _, axis := range opts.matrix {
+		used := false
+		for _, t := range tasksToRun {
+			used = used || declaresArg(t, axis.Arg)
+		}
+		if !used {
+			unusedArgs = append(unusedArgs, matrixNamespace+":"+axis.Arg)
+		}
+	}
+	if len(unusedArgs) > 0 {
+		for _, unusedArg := range unusedArgs {
+			rep.unusedArg(unusedArg)
+		}
+		if registry.shouldErrorOnUnusedArgs {
+			return fmt.Errorf("unused args")
+		}
+	}
+
+	r := &runner{
+		registry:          registry,
+		opts:              opts,
+		rep:               rep,
+		allTasks:          taskLookup(registry.Tasks()),
+		executed:          make(map[string]bool),
+		pendingFinalizers: make(map[string]int),
+		finalizedResults:  make(map[string]*TaskResult),
+		outputs:           newOutputStore(),
+		durations:         make(map[string]time.Duration),
+	}
+	for _, t := range tasksToRun {
+		for _, name := range r.finalizers(t) {
+			r.pendingFinalizers[name]++
+		}
+	}
+
+	totalStartTime := time.Now()
+	rep.runStarted(tasksToRun)
+
+	err := r.runPlan(tasksToRun)
+
+	for _, t := range tasksToRun {
+		if !r.executed[t.Name()] {
+			r.results = append(r.results, &TaskResult{Task: t.Name(), Status: StatusNotRun})
+		}
+	}
+
+	if err == nil {
+		r.runDeferred()
+	}
+
+	for _, regression := range registry.history.regressions(r.results) {
+		rep.durationRegressed(regression)
+	}
+
+	totalDuration := time.Since(totalStartTime)
+	if opts.criticalPath {
+		// the tasks which didn't run take as long as they did in previous runs
+		durations := registry.history.estimates()
+		if durations == nil {
+			durations = make(map[string]time.Duration, len(r.durations))
+		}
+		for name, d := range r.durations {
+			durations[name] = d
+		}
+		path, pathDuration := criticalPath(tasksToRun, durations)
+		rep.criticalPath(path, durations, pathDuration, totalDuration)
+	}
+	rep.runCompleted(totalDuration, r.results, r.failedTasks)
+
+	if err != nil {
+		return err
+	}
+
+	if len(r.failedTasks) > 0 {
+		return fmt.Errorf("task(s) %s failed", r.failedTasks)
+	}
+
+	return nil
+}
+
+type runner struct {
+	registry *Registry
+	opts     *runOptions
+	rep      reporter
+	allTasks map[string]Task
+	outputs  *outputStore
+
+	executed          map[string]bool
+	durations         map[string]time.Duration
+	failedTasks       []string
+	results           []*TaskResult
+	deferredTaskNames []string
+
+	// pendingFinalizers counts, for each finalizer, the planned tasks it finalizes which have not yet run.
+	pendingFinalizers map[string]int
+	// finalizedResults holds the result of the task most recently finalized by each finalizer.
+	finalizedResults  map[string]*TaskResult
+	startedFinalizers []string
+}
+
+// runPlan runs the tasks in order until one fails without ContinueOnError. Finalizers of the
+// tasks which ran are always run.
+func (r *runner) runPlan(tasksToRun []Task) error {
+	defer r.runStartedFinalizers()
+
+	for _, t := range tasksToRun {
+		if r.executed[t.Name()] {
+			// already ran as a finalizer
+			continue
+		}
+
+		result, err := r.runTask(t, nil)
+		if err != nil {
+			// the task failed before it could run, but its finalizers still run
+			result = newTaskResult(t.Name(), err, 0)
+			r.results = append(r.results, result)
+			r.finish(t, result)
+			return err
+		}
+
+		if r.finish(t, result) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// runTask runs a single task, returning a nil result for aggregate tasks.
+func (r *runner) runTask(t Task, finalized *TaskResult) (*TaskResult, error) {
+	r.executed[t.Name()] = true
+
+	if d := taskDeprecation(t); d != nil {
+		r.rep.deprecated(t, d)
+	}
+
+	r.deferredTaskNames = append(t.DeferredTasks(), r.deferredTaskNames...)
+
+	executor := r.registry.executor(t)
+	if executor == nil {
+		// this task is just an aggregate task
+		r.results = append(r.results, &TaskResult{Task: t.Name(), Status: StatusOK})
+		return nil, nil
+	}
+
+	if axes := r.matrixAxes(t); len(axes) > 0 {
+		result := r.runMatrix(t, executor, axes, finalized)
+		r.durations[t.Name()] = result.Duration
+		return result, nil
+	}
+
+	taskArgs, err := argsForTask(t, r.opts.args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := NewContext(context.Background(), r.rep.writer(), taskArgs, r.contextParams(t, finalized)...)
+
+	r.rep.taskStarted(t)
+	startTime := time.Now()
+	err = runExecutor(executor, ctx)
+	result := newTaskResult(t.Name(), err, time.Since(startTime))
+	r.rep.taskFinished(t, result)
+	r.results = append(r.results, result)
+	r.durations[t.Name()] = result.Duration
+
+	return result, nil
+}
+
+// contextParams returns the parameters for the Context of the task.
+func (r *runner) contextParams(t Task, finalized *TaskResult) []ContextParam {
+	params := append(r.rep.contextParams(),
+		withOutputs(t.Name(), r.outputs, dependencyResolver(r.allTasks, t, finalized)),
+		withSecrets(r.opts.secrets))
+	if finalized != nil {
+		params = append(params, withFinalizedResult(finalized))
+	}
+	if p, ok := t.(parameterizedTask); ok {
+		params = append(params, withParam(p.Param()))
+	}
+	return params
+}
+
+// finish records the result of a task and runs any finalizers which are no longer waiting on
+// other tasks. It returns whether the run should stop.
+func (r *runner) finish(t Task, result *TaskResult) bool {
+	stop := false
+	if result != nil && result.Err != nil {
+		r.failedTasks = append(r.failedTasks, t.Name())
+		stop = !t.ContinueOnError()
+	}
+
+	if result == nil {
+		result = &TaskResult{Task: t.Name()}
+	}
+
+	for _, name := range r.finalizers(t) {
+		if _, ok := r.finalizedResults[name]; !ok {
+			r.startedFinalizers = append(r.startedFinalizers, name)
+		}
+		r.finalizedResults[name] = result
+		if r.pendingFinalizers[name] > 0 {
+			r.pendingFinalizers[name]--
+		}
+		if r.pendingFinalizers[name] == 0 && !r.executed[name] {
+			if r.runFinalizer(name) {
+				stop = true
+			}
+		}
+	}
+
+	return stop
+}
+
+// runStartedFinalizers runs the finalizers of tasks which ran but have not yet been run themselves,
+// as happens when the run stops early.
+func (r *runner) runStartedFinalizers() {
+	for _, name := range r.startedFinalizers {
+		if !r.executed[name] {
+			r.runFinalizer(name)
+		}
+	}
+}
+
+// runFinalizer runs the named finalizer along with any of its dependencies which have not yet run.
+// It returns whether the run should stop.
+func (r *runner) runFinalizer(name string) bool {
+	tasks, err := sortTasksToRun(r.registry.Tasks(), []string{name})
+	if err != nil {
+		// should not happen since finalizers are validated when building the primary task list
+		r.failedTasks = append(r.failedTasks, name)
+		return true
+	}
+
+	for _, t := range tasks {
+		if r.executed[t.Name()] {
+			continue
+		}
+
+		var finalized *TaskResult
+		if t.Name() == name {
+			finalized = r.finalizedResults[name]
+		}
+
+		result, err := r.runTask(t, finalized)
+		if err != nil {
+			result = newTaskResult(t.Name(), err, 0)
+			r.rep.taskStarted(t)
+			r.rep.taskFinished(t, result)
+			r.results = append(r.results, result)
+		}
+
+		if r.finish(t, result) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runDeferred runs the deferred tasks of every task which ran. Failures are reported but do not fail the run.
+func (r *runner) runDeferred() {
+	deferredTasks, err := sortTasksToRun(r.registry.Tasks(), r.deferredTaskNames)
+	if err != nil {
+		r.rep.deferredFailed(err)
+		return
+	}
+	if len(deferredTasks) == 0 {
+		return
+	}
+
+	r.rep.deferredStarted()
+	startTime := time.Now()
+	for _, task := range deferredTasks {
+		if executor := r.registry.executor(task); executor != nil {
+			taskArgs, err := argsForTask(task, r.opts.args)
+			if err != nil {
+				r.rep.deferredSkipped(task, err)
+				r.results = append(r.results, &TaskResult{Task: task.Name(), Status: StatusSkipped, Err: err})
+				continue
+			}
+
+			ctx := NewContext(context.Background(), r.rep.writer(), taskArgs, r.contextParams(task, nil)...)
+			taskStartTime := time.Now()
+			err = runExecutor(executor, ctx)
+			result := newTaskResult(task.Name(), err, time.Since(taskStartTime))
+			r.rep.deferredFinished(task, result)
+			r.results = append(r.results, result)
+		}
+	}
+	r.rep.deferredCompleted(time.Since(startTime))
+}
+
+// finalizers returns the canonical names of the task's finalizers.
+func (r *runner) finalizers(t Task) []string {
+	names := make([]string, 0, len(taskFinalizedBy(t)))
+	for _, name := range taskFinalizedBy(t) {
+		if f, ok := r.allTasks[strings.ToLower(name)]; ok {
+			name = f.Name()
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,54 @@
+package task
+
+import (
+	"os"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+// defaultSecretEnvVars are well-known environment variables holding credentials, whose values
+// are masked in all output.
+var defaultSecretEnvVars = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+}
+
+// newSecrets makes the masker of a run, masking the values of the well-known environment variables.
+func newSecrets() *internal.Masker {
+	secrets := internal.NewMasker()
+	for _, name := range defaultSecretEnvVars {
+		secrets.Add(os.Getenv(name))
+	}
+	return secrets
+}
+
+// registerSecretArgs masks the values of the tasks' secret arguments, whether given to the task or
+// globally. Since Context.Get falls back to the environment, environment variables named after
+// secret arguments are masked as well.
+func registerSecretArgs(tasks []Task, opts *runOptions) {
+	for _, t := range tasks {
+		for _, da := range t.DeclaredArgs() {
+			if !da.Secret {
+				continue
+			}
+			opts.secrets.Add(da.Default, os.Getenv(da.Name))
+			for _, ns := range taskArgNamespaces(t) {
+				if v, ok := opts.args.get(ns, da.Name); ok {
+					opts.secrets.Add(v)
+				}
+			}
+			if v, ok := opts.args.get("", da.Name); ok {
+				opts.secrets.Add(v)
+			}
+		}
+	}
+}
+
+// maskArg masks the value of a secret argument.
+func maskArg(da DeclaredTaskArg, value string) string {
+	if da.Secret && value != "" {
+		return internal.Mask
+	}
+	return value
+}
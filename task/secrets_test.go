@@ -0,0 +1,125 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretMasking(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv("AWS_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "aws-secret")
+
+	registry := NewRegistry()
+	registry.Declare("deploy").
+		SecretArg("token").
+		Do(func(ctx *Context) error {
+			ctx.RegisterSecret(`session"id`)
+			// a secret which is also part of what goke writes
+			ctx.RegisterSecret("task")
+			ctx.Logf("deploying with %s\n", ctx.Get("token"))
+			ctx.Logln(`{"session":"session\"id"}`)
+			ctx.Logln("credentials:", os.Getenv("AWS_SECRET_ACCESS_KEY"))
+			// a secret split across writes
+			token := ctx.Get("token")
+			ctx.Log("again with " + token[:3])
+			ctx.Log(token[3:] + "\n")
+			ctx.Log("done")
+			return nil
+		})
+
+	jsonOut := filepath.Join(dir, "events.jsonl")
+	logFile := filepath.Join(dir, "build.log")
+
+	// the secret is masked whether given to the task or globally
+	for _, tokenArg := range []string{"-deploy:token=hunter22", "-token=hunter22"} {
+		err = Run(registry, []string{"deploy", tokenArg, "-progress=false", "-json-out=" + jsonOut, "-log-file=" + logFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, path := range []string{jsonOut, logFile} {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, secret := range []string{"hunter22", `session"id`, `session\"id`, "aws-secret"} {
+				if strings.Contains(string(data), secret) {
+					t.Errorf("%s: expected %q to be masked in %s but got:\n%s", tokenArg, secret, filepath.Base(path), data)
+				}
+			}
+		}
+
+		events, _ := ioutil.ReadFile(jsonOut)
+		if !strings.Contains(string(events), `"event":"task_start"`) {
+			t.Errorf("%s: expected the event types to be left intact in:\n%s", tokenArg, events)
+		}
+
+		log, _ := ioutil.ReadFile(logFile)
+		for _, line := range []string{"deploying with ****\n", "credentials: ****\n", "again with ****\n", "| done", "1 task(s): 1 ok"} {
+			if !strings.Contains(string(log), line) {
+				t.Errorf("%s: expected %q in:\n%s", tokenArg, line, log)
+			}
+		}
+	}
+}
+
+func TestContextWriteHoldsPartialSecrets(t *testing.T) {
+	var out bytes.Buffer
+	ctx := NewContext(context.Background(), &out, nil)
+
+	// without secrets, output is written as it is
+	ctx.Log("Enter: ")
+	if out.String() != "Enter: " {
+		t.Fatalf("expected the prompt to be written but got %q", out.String())
+	}
+
+	ctx.RegisterSecret("hunter22")
+	ctx.Log("Enter: ")
+	if out.String() != "Enter: Enter: " {
+		t.Fatalf("expected the prompt to be written but got %q", out.String())
+	}
+
+	ctx.Log("token hun")
+	if out.String() != "Enter: Enter: token " {
+		t.Fatalf("expected the beginning of the secret to be held but got %q", out.String())
+	}
+	ctx.Log("ter22 and hunt")
+	ctx.Log("ing")
+	if out.String() != "Enter: Enter: token **** and hunting" {
+		t.Fatalf("expected the secret to be masked but got %q", out.String())
+	}
+
+	ctx.Log(" hunter")
+	if err := ctx.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "Enter: Enter: token **** and hunting hunter" {
+		t.Fatalf("expected the held output to be flushed but got %q", out.String())
+	}
+}
+
+func TestSecretArgUsage(t *testing.T) {
+	registry := NewRegistry()
+	registry.Declare("deploy").
+		DefaultArg("token", "default-token").
+		SecretArg("token").
+		Do(func(ctx *Context) error { return nil })
+	deploy, _ := registry.findTask("deploy")
+
+	var buf bytes.Buffer
+	taskUsage(nil, registry, deploy, 80, &buf)
+	if !strings.Contains(buf.String(), `token (optional, secret, default "****")`) {
+		t.Errorf("expected the default to be masked but got:\n%s", buf.String())
+	}
+}
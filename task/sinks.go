@@ -0,0 +1,95 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+// sinks are the additional outputs of a run, given by -json-out and -log-file, which are
+// written alongside the output on stdout.
+type sinks struct {
+	reporters []reporter
+	files     []*os.File
+}
+
+// openSinks creates the files of the sinks. The JSON sink receives the event stream and the log
+// sink receives human output, each uncolored unless -json-out-color or -log-file-color is set.
+// The log sink has its own -log-file-timestamps.
+func openSinks(opts *runOptions, theme Theme) (*sinks, error) {
+	s := &sinks{}
+	if opts.jsonOut != "" {
+		f, err := s.create(opts.jsonOut)
+		if err != nil {
+			return nil, err
+		}
+		logger := newJSONLogger(&syncWriter{Writer: f}, opts)
+		logger.SetStripANSI(!opts.jsonOutColor)
+		s.reporters = append(s.reporters, &jsonReporter{logger: logger, verbose: opts.verbose})
+	}
+	if opts.logFile != "" {
+		f, err := s.create(opts.logFile)
+		if err != nil {
+			return nil, err
+		}
+		w := newPrefixWriter(&syncWriter{Writer: f}, opts)
+		w.SetTimestamps(timestampFormat(opts.logFileTimestamps, opts.startTime))
+		w.SetStripANSI(!opts.logFileColor)
+		s.reporters = append(s.reporters, &humanReporter{ui: newTUI(opts.logFileColor, theme), w: w, verbose: opts.verbose, secrets: opts.secrets})
+	}
+	return s, nil
+}
+
+func (s *sinks) create(path string) (*os.File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed creating output: %w", err)
+	}
+	s.files = append(s.files, f)
+	return f, nil
+}
+
+// reporter returns a reporter which reports to rep and each of the sinks.
+func (s *sinks) reporter(rep reporter) reporter {
+	if len(s.reporters) == 0 {
+		return rep
+	}
+	return append(multiReporter{rep}, s.reporters...)
+}
+
+func (s *sinks) close() {
+	for _, f := range s.files {
+		_ = f.Close()
+	}
+}
+
+// newPrefixWriter makes the PrefixWriter for human output, adding timestamps as given by -timestamps.
+func newPrefixWriter(w io.Writer, opts *runOptions) *internal.PrefixWriter {
+	pw := internal.NewPrefixWriter(w)
+	pw.SetTimestamps(timestampFormat(opts.timestamps, opts.startTime))
+	return pw
+}
+
+// timestampFormat returns how the time of each line is formatted for the -timestamps setting,
+// or nil without timestamps.
+func timestampFormat(timestamps string, startTime time.Time) func(time.Time) string {
+	switch timestamps {
+	case timestampsAbsolute:
+		return internal.AbsoluteTimestamps
+	case timestampsRelative:
+		return internal.RelativeTimestamps(startTime)
+	default:
+		return nil
+	}
+}
+
+// newJSONLogger makes the JSONLogger for the events of the run.
+func newJSONLogger(w io.Writer, opts *runOptions) *internal.JSONLogger {
+	logger := internal.NewJSONLogger(w, opts.runID)
+	logger.SetMasker(opts.secrets)
+	return logger
+}
@@ -0,0 +1,89 @@
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/craiggwilson/goke/task/event"
+)
+
+func TestSinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	registry := NewRegistry()
+	registry.Declare("greet").Do(func(ctx *Context) error {
+		// colored both by the context's UI and by the raw output of a tool
+		ctx.Logln(ctx.UI.Success("hello"))
+		ctx.Logln("\x1b[31mworld\x1b[0m")
+		return nil
+	})
+
+	jsonOut := filepath.Join(dir, "events.jsonl")
+	logFile := filepath.Join(dir, "build.log")
+	if err := Run(registry, []string{"greet", "-color=true", "-progress=false", "-json-out=" + jsonOut, "-log-file=" + logFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(log), "START  | greet\n       | hello\n       | world\nFINISH | greet in ") {
+		t.Errorf("expected an uncolored log but got:\n%s", log)
+	}
+
+	f, err := os.Open(jsonOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var types, lines []string
+	d := event.NewDecoder(f)
+	for {
+		e, err := d.Decode()
+		if err != nil {
+			break
+		}
+		types = append(types, string(e.Type))
+		if e.Type == event.TaskOutput {
+			lines = append(lines, e.Line)
+		}
+	}
+	expected := "run_start,task_start,task_output,task_output,task_finish,run_finish"
+	if strings.Join(types, ",") != expected {
+		t.Errorf("expected the events %s but got %s", expected, strings.Join(types, ","))
+	}
+	if strings.Join(lines, ",") != "hello,world" {
+		t.Errorf("expected uncolored output events but got %q", lines)
+	}
+
+	// each sink may keep its colors and have its own timestamps
+	if err := Run(registry, []string{"greet", "-color=true", "-progress=false", "-json-out=" + jsonOut, "-log-file=" + logFile,
+		"-json-out-color", "-log-file-color", "-log-file-timestamps=rel"}); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err = ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(log), "\x1b[31mworld\x1b[0m") || !strings.HasPrefix(strings.TrimLeft(string(log), " "), "0.") {
+		t.Errorf("expected a colored log with relative timestamps but got:\n%s", log)
+	}
+
+	events, err := ioutil.ReadFile(jsonOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(events), `"line":"\u001b[31mworld\u001b[0m"`) {
+		t.Errorf("expected colored output events but got:\n%s", events)
+	}
+}
@@ -3,6 +3,7 @@ package task
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 type state = uint8
@@ -27,11 +28,104 @@ func sortTasksToRun(allTasks []Task, requiredTaskNames []string) ([]Task, error)
 	return result, nil
 }
 
-func buildGraph(allTasks []Task, requiredTaskNames []string) ([]*graphNode, error) {
+// scheduleTasksToRun sorts the tasks like sortTasksToRun, but whenever more than one task is ready
+// to run, the task with the longest estimated chain of tasks waiting on it runs first, so that the
+// critical path of the plan isn't held up by tasks which could run later.
+func scheduleTasksToRun(allTasks []Task, requiredTaskNames []string, estimates map[string]time.Duration) ([]Task, error) {
+	graph, err := buildGraph(allTasks, requiredTaskNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return prioritizedToposort(graph, remainingDurations(graph, estimates))
+}
+
+// remainingDurations returns the estimated duration of each task in the graph followed by the
+// longest chain of tasks waiting on it.
+func remainingDurations(g []*graphNode, estimates map[string]time.Duration) map[string]time.Duration {
+	if len(estimates) == 0 {
+		return nil
+	}
+
+	waiting := make(map[string][]string, len(g))
+	for _, n := range g {
+		for _, edge := range n.edges {
+			waiting[edge] = append(waiting[edge], n.task.Name())
+		}
+	}
+
+	remaining := make(map[string]time.Duration, len(g))
+	visiting := make(map[string]bool)
+	var visit func(string) time.Duration
+	visit = func(name string) time.Duration {
+		if d, ok := remaining[name]; ok || visiting[name] {
+			// a cycle is reported by the sort
+			return d
+		}
+		visiting[name] = true
+		var longest time.Duration
+		for _, dependent := range waiting[name] {
+			if d := visit(dependent); d > longest {
+				longest = d
+			}
+		}
+		remaining[name] = estimates[name] + longest
+		return remaining[name]
+	}
+	for _, n := range g {
+		visit(n.task.Name())
+	}
+	return remaining
+}
+
+// taskLookup maps lower-cased task names and aliases to their tasks. Task names
+// take precedence over aliases, though such collisions are reported by aliasConflicts.
+func taskLookup(allTasks []Task) map[string]Task {
 	allTasksMap := make(map[string]Task)
 	for _, t := range allTasks {
 		allTasksMap[strings.ToLower(t.Name())] = t
 	}
+	for _, t := range allTasks {
+		for _, alias := range taskAliases(t) {
+			if _, ok := allTasksMap[strings.ToLower(alias)]; !ok {
+				allTasksMap[strings.ToLower(alias)] = t
+			}
+		}
+	}
+	return allTasksMap
+}
+
+// aliasConflicts describes the aliases which are also the name or an alias of another task,
+// since a name must refer to a single task.
+func aliasConflicts(allTasks []Task) []string {
+	owners := make(map[string]Task)
+	for _, t := range allTasks {
+		owners[strings.ToLower(t.Name())] = t
+	}
+
+	var conflicts []string
+	for _, t := range allTasks {
+		for _, alias := range taskAliases(t) {
+			owner, ok := owners[strings.ToLower(alias)]
+			switch {
+			case !ok:
+				owners[strings.ToLower(alias)] = t
+			case owner == t:
+			case strings.EqualFold(owner.Name(), alias):
+				conflicts = append(conflicts, fmt.Sprintf("alias %q of task %q is the name of another task", alias, t.Name()))
+			default:
+				conflicts = append(conflicts, fmt.Sprintf("alias %q of task %q is also an alias of task %q", alias, t.Name(), owner.Name()))
+			}
+		}
+	}
+	return conflicts
+}
+
+func buildGraph(allTasks []Task, requiredTaskNames []string) ([]*graphNode, error) {
+	if conflicts := aliasConflicts(allTasks); len(conflicts) > 0 {
+		return nil, fmt.Errorf("ambiguous task names: %s", strings.Join(conflicts, "; "))
+	}
+	allTasksMap := taskLookup(allTasks)
 
 	var g []*graphNode
 	seenTasks := make(map[string]struct{})
@@ -50,22 +144,120 @@ func buildGraph(allTasks []Task, requiredTaskNames []string) ([]*graphNode, erro
 			if err := validateDeferredTasks(allTasksMap, deferredTaskStates, task.DeferredTasks()); err != nil {
 				return nil, err
 			}
-			// toposort modifies edges, copying task dependencies here avoids inadvertent changes to the task object itself
-			g = append(g, &graphNode{task: task, edges: append([]string{}, task.Dependencies()...)})
+			for _, finalizer := range taskFinalizedBy(task) {
+				if _, ok := allTasksMap[strings.ToLower(finalizer)]; !ok {
+					return nil, fmt.Errorf("unknown task '%s'", finalizer)
+				}
+			}
+			// toposort modifies edges, copying task dependencies here avoids inadvertent changes to the task object itself.
+			// Dependencies may be referenced by alias or in a different case, so edges use the canonical names.
+			n := &graphNode{task: task}
+			for _, dep := range task.Dependencies() {
+				if depTask, ok := allTasksMap[strings.ToLower(dep)]; ok {
+					dep = depTask.Name()
+				}
+				n.addEdge(dep)
+			}
+			g = append(g, n)
 
 			requiredTaskNames = append(requiredTaskNames, task.Dependencies()...)
 		}
 	}
 
+	addOrderingConstraints(allTasksMap, g)
+
 	return g, nil
 }
 
+// addOrderingConstraints adds edges for the must-run-after and should-run-after constraints between
+// tasks which are both in the graph, and orders finalizers in the graph after the tasks they
+// finalize. Should-run-after constraints and finalizer orderings which would create a cycle are dropped.
+func addOrderingConstraints(allTasksMap map[string]Task, g []*graphNode) {
+	nodes := make(map[string]*graphNode, len(g))
+	for _, n := range g {
+		nodes[n.task.Name()] = n
+	}
+	inGraph := func(name string) (string, bool) {
+		t, ok := allTasksMap[strings.ToLower(name)]
+		if !ok {
+			return "", false
+		}
+		_, ok = nodes[t.Name()]
+		return t.Name(), ok
+	}
+
+	for _, n := range g {
+		for _, name := range taskMustRunAfter(n.task) {
+			if name, ok := inGraph(name); ok {
+				n.addEdge(name)
+			}
+		}
+	}
+
+	for _, n := range g {
+		for _, name := range taskFinalizedBy(n.task) {
+			if name, ok := inGraph(name); ok && !reaches(nodes, n.task.Name(), name) {
+				nodes[name].addEdge(n.task.Name())
+			}
+		}
+	}
+
+	for _, n := range g {
+		for _, name := range taskShouldRunAfter(n.task) {
+			if name, ok := inGraph(name); ok && !reaches(nodes, name, n.task.Name()) {
+				n.addEdge(name)
+			}
+		}
+	}
+}
+
+// reaches indicates whether the node named to is reachable by following edges from the node named from.
+func reaches(nodes map[string]*graphNode, from string, to string) bool {
+	visited := make(map[string]bool)
+	var visit func(string) bool
+	visit = func(name string) bool {
+		if name == to {
+			return true
+		}
+		if visited[name] {
+			return false
+		}
+		visited[name] = true
+		if n, ok := nodes[name]; ok {
+			for _, edge := range n.edges {
+				if visit(edge) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	return visit(from)
+}
+
 type graphNode struct {
 	task  Task
 	edges []string
 }
 
+// addEdge adds an edge to the named task unless one already exists.
+func (n *graphNode) addEdge(name string) {
+	for _, edge := range n.edges {
+		if edge == name {
+			return
+		}
+	}
+	n.edges = append(n.edges, name)
+}
+
 func toposort(g []*graphNode) ([]Task, error) {
+	return prioritizedToposort(g, nil)
+}
+
+// prioritizedToposort sorts the graph, choosing the ready task with the highest priority first and,
+// among those with the same priority, the one which became ready first.
+func prioritizedToposort(g []*graphNode, priorities map[string]time.Duration) ([]Task, error) {
 	var queue []*graphNode
 	for _, n := range g {
 		if len(n.edges) == 0 {
@@ -75,8 +267,14 @@ func toposort(g []*graphNode) ([]Task, error) {
 
 	var sorted []Task
 	for len(queue) > 0 {
-		n := queue[0]
-		queue = queue[1:]
+		next := 0
+		for i, n := range queue {
+			if priorities[n.task.Name()] > priorities[queue[next].task.Name()] {
+				next = i
+			}
+		}
+		n := queue[next]
+		queue = append(queue[:next], queue[next+1:]...)
 		sorted = append(sorted, n.task)
 		for _, m := range g {
 			for i := range m.edges {
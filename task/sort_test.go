@@ -3,6 +3,7 @@ package task
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestToposort(t *testing.T) {
@@ -101,3 +102,79 @@ func (t dummyTask) Name() string {
 func (t dummyTask) DeferredTasks() []string {
 	return nil
 }
+
+func TestOrderingConstraints(t *testing.T) {
+	reg := NewRegistry()
+	reg.Declare("clean")
+	reg.Declare("compile")
+	reg.Declare("build").DependsOn("compile").MustRunAfter("clean")
+	reg.Declare("docs").ShouldRunAfter("build")
+	reg.Declare("lint").ShouldRunAfter("test")
+	reg.Declare("test").DependsOn("lint")
+
+	testCases := []struct {
+		taskNames []string
+		expected  string
+	}{
+		{[]string{"build", "clean"}, "[clean compile build]"},
+		// clean is not pulled in by the ordering constraint
+		{[]string{"build"}, "[compile build]"},
+		{[]string{"docs", "build"}, "[compile build docs]"},
+		// the soft constraint would cause a cycle, so it is dropped
+		{[]string{"test"}, "[lint test]"},
+	}
+
+	for _, tc := range testCases {
+		result, err := sortTasksToRun(reg.Tasks(), tc.taskNames)
+		if err != nil {
+			t.Fatalf("%v: expected no error, but got %s", tc.taskNames, err)
+		}
+		var names []string
+		for _, task := range result {
+			names = append(names, task.Name())
+		}
+		if fmt.Sprint(names) != tc.expected {
+			t.Fatalf("%v: expected %s, but got %s", tc.taskNames, tc.expected, names)
+		}
+	}
+
+	reg.Declare("a").MustRunAfter("b")
+	reg.Declare("b").MustRunAfter("a")
+	if _, err := sortTasksToRun(reg.Tasks(), []string{"a", "b"}); err == nil {
+		t.Fatal("expected an error for conflicting must-run-after constraints, but got none")
+	}
+}
+
+func TestScheduleTasksToRun(t *testing.T) {
+	reg := NewRegistry()
+	reg.Declare("lint")
+	reg.Declare("compile")
+	reg.Declare("test").DependsOn("compile")
+	reg.Declare("docs").MustRunAfter("lint")
+	reg.Declare("all").DependsOn("lint", "docs", "test")
+
+	testCases := []struct {
+		estimates map[string]time.Duration
+		expected  string
+	}{
+		{nil, "[lint compile docs test all]"},
+		// compile is on the critical path, since test waits on it
+		{map[string]time.Duration{"lint": 2 * time.Second, "compile": time.Second, "test": 5 * time.Second}, "[compile test lint docs all]"},
+		// docs must run after lint, so lint runs first
+		{map[string]time.Duration{"compile": time.Second, "docs": 10 * time.Second}, "[lint docs compile test all]"},
+	}
+
+	for _, tc := range testCases {
+		result, err := scheduleTasksToRun(reg.Tasks(), []string{"all"}, tc.estimates)
+		if err != nil {
+			t.Fatalf("%v: expected no error, but got %s", tc.estimates, err)
+		}
+		var names []string
+		for _, task := range result {
+			names = append(names, task.Name())
+		}
+		if fmt.Sprint(names) != tc.expected {
+			t.Errorf("%v: expected %s, but got %s", tc.estimates, tc.expected, names)
+		}
+	}
+}
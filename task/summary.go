@@ -0,0 +1,119 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/craiggwilson/goke/task/internal"
+)
+
+// slowestTaskCount is the number of slowest tasks highlighted in the summary.
+const slowestTaskCount = 3
+
+var summaryStatuses = []TaskStatus{StatusOK, StatusFailed, StatusSkipped, StatusNotRun}
+
+// printSummary prints a table of the results, highlighting the slowest tasks, followed by a count of each status.
+// Secrets are masked in the errors of the results.
+func printSummary(ui *TUI, out io.Writer, results []*TaskResult, secrets *internal.Masker) {
+	if len(results) == 0 {
+		return
+	}
+
+	nameWidth, statusWidth := 0, 0
+	for _, result := range results {
+		if len(result.Task) > nameWidth {
+			nameWidth = len(result.Task)
+		}
+		if len(result.Status) > statusWidth {
+			statusWidth = len(result.Status)
+		}
+	}
+
+	slowest := slowestResults(results)
+
+	fmt.Fprintln(out, ui.Highlight("SUMMARY")+":")
+	for _, result := range results {
+		status := colorStatus(ui, result.Status, pad(string(result.Status), statusWidth))
+		name := ui.TaskName(pad(result.Task, nameWidth))
+
+		line := fmt.Sprintf("  %s  %s", status, name)
+		if result.Duration > 0 {
+			duration := result.Duration.Round(time.Microsecond).String()
+			if slowest[result] {
+				duration = ui.Warning(duration + " (slow)")
+			} else {
+				duration = ui.Duration(duration)
+			}
+			line += "  " + duration
+		}
+		if result.Err != nil {
+			line += "  " + ui.Lowlight(firstLine(secrets.Mask(result.Err.Error())))
+		}
+		fmt.Fprintln(out, strings.TrimRight(line, " "))
+	}
+
+	counts := make(map[TaskStatus]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	var parts []string
+	for _, status := range summaryStatuses {
+		if counts[status] > 0 {
+			parts = append(parts, colorStatus(ui, status, fmt.Sprintf("%d %s", counts[status], status)))
+		}
+	}
+	fmt.Fprintf(out, "%d task(s): %s\n", len(results), strings.Join(parts, ", "))
+}
+
+// slowestResults returns the slowest of the results which ran when there are more of them than slowestTaskCount.
+func slowestResults(results []*TaskResult) map[*TaskResult]bool {
+	var ran []*TaskResult
+	for _, result := range results {
+		if result.Duration > 0 {
+			ran = append(ran, result)
+		}
+	}
+	if len(ran) <= slowestTaskCount {
+		return nil
+	}
+
+	sort.SliceStable(ran, func(i, j int) bool {
+		return ran[i].Duration > ran[j].Duration
+	})
+
+	slowest := make(map[*TaskResult]bool, slowestTaskCount)
+	for _, result := range ran[:slowestTaskCount] {
+		slowest[result] = true
+	}
+	return slowest
+}
+
+func colorStatus(ui *TUI, status TaskStatus, msg string) string {
+	switch status {
+	case StatusOK:
+		return ui.Success(msg)
+	case StatusFailed:
+		return ui.Error(msg)
+	case StatusSkipped:
+		return ui.Warning(msg)
+	default:
+		return ui.Lowlight(msg)
+	}
+}
+
+func pad(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+package task
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPrintSummary(t *testing.T) {
+	results := []*TaskResult{
+		{Task: "clean", Status: StatusOK, Duration: time.Millisecond},
+		{Task: "compile", Status: StatusOK, Duration: 3 * time.Second},
+		{Task: "sa", Status: StatusOK},
+		{Task: "test", Status: StatusFailed, Duration: 2 * time.Second, Err: errors.New("exit status 1\nmore details")},
+		{Task: "lint", Status: StatusOK, Duration: time.Second},
+		{Task: "package", Status: StatusNotRun},
+		{Task: "notify", Status: StatusSkipped, Err: errors.New("argument missing")},
+	}
+
+	var buf bytes.Buffer
+	printSummary(nil, &buf, results, nil)
+
+	expected := `SUMMARY:
+  ok       clean    1ms
+  ok       compile  3s (slow)
+  ok       sa
+  failed   test     2s (slow)  exit status 1
+  ok       lint     1s (slow)
+  not run  package
+  skipped  notify   argument missing
+7 task(s): 4 ok, 1 failed, 1 skipped, 1 not run
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
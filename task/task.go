@@ -14,6 +14,117 @@ type Task interface {
 	DeferredTasks() []string
 }
 
+// The optional capabilities of a Task. Tasks made with a Builder have all of them, while other
+// implementations of Task only need the methods of the capabilities they use.
+type (
+	aliasedTask interface {
+		Aliases() []string
+	}
+	deprecatedTask interface {
+		Deprecation() *Deprecation
+	}
+	finalizedTask interface {
+		FinalizedBy() []string
+	}
+	longDescribedTask interface {
+		LongDescription() string
+	}
+	matrixTask interface {
+		Matrix() []MatrixAxis
+	}
+	orderedTask interface {
+		MustRunAfter() []string
+		ShouldRunAfter() []string
+	}
+	quietTask interface {
+		Quiet() bool
+	}
+	resourceTask interface {
+		Resources() []string
+	}
+)
+
+func taskAliases(t Task) []string {
+	if at, ok := t.(aliasedTask); ok {
+		return at.Aliases()
+	}
+	return nil
+}
+
+func taskDeprecation(t Task) *Deprecation {
+	if dt, ok := t.(deprecatedTask); ok {
+		return dt.Deprecation()
+	}
+	return nil
+}
+
+func taskFinalizedBy(t Task) []string {
+	if ft, ok := t.(finalizedTask); ok {
+		return ft.FinalizedBy()
+	}
+	return nil
+}
+
+func taskLongDescription(t Task) string {
+	if lt, ok := t.(longDescribedTask); ok {
+		return lt.LongDescription()
+	}
+	return ""
+}
+
+func taskMatrix(t Task) []MatrixAxis {
+	if mt, ok := t.(matrixTask); ok {
+		return mt.Matrix()
+	}
+	return nil
+}
+
+func taskMustRunAfter(t Task) []string {
+	if ot, ok := t.(orderedTask); ok {
+		return ot.MustRunAfter()
+	}
+	return nil
+}
+
+func taskShouldRunAfter(t Task) []string {
+	if ot, ok := t.(orderedTask); ok {
+		return ot.ShouldRunAfter()
+	}
+	return nil
+}
+
+func taskQuiet(t Task) bool {
+	if qt, ok := t.(quietTask); ok {
+		return qt.Quiet()
+	}
+	return false
+}
+
+func taskResources(t Task) []string {
+	if rt, ok := t.(resourceTask); ok {
+		return rt.Resources()
+	}
+	return nil
+}
+
+// Deprecation describes why a task is deprecated and what should be used instead.
+type Deprecation struct {
+	Message     string `json:"message"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// String returns the warning shown when a deprecated task runs.
+func (d *Deprecation) String() string {
+	msg := d.Message
+	if d.Replacement != "" {
+		if msg != "" {
+			msg += "; "
+		}
+		msg += fmt.Sprintf("use '%s' instead", d.Replacement)
+	}
+	return msg
+}
+
 type sortedTasks []Task
 
 func (a sortedTasks) Len() int           { return len(a) }
@@ -50,5 +161,18 @@ func ChainValidator(validators ...Validator) Validator {
 // DeclaredTaskArg is an argument for a particular task.
 type DeclaredTaskArg struct {
 	Name      string
+	Default   string
 	Validator Validator
+	// Secret is whether the value of the argument is masked in all output.
+	Secret bool
+}
+
+// IsRequired indicates whether the argument must be supplied. An argument is
+// required when it has no default and its validator rejects an empty value.
+func (a DeclaredTaskArg) IsRequired() bool {
+	if a.Default != "" || a.Validator == nil {
+		return false
+	}
+
+	return a.Validator(a.Name, "") != nil
 }
@@ -0,0 +1,30 @@
+package task
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/mattn/go-isatty"
+)
+
+const defaultTerminalWidth = 80
+
+// isTerminal indicates whether the file is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// terminalWidth returns the width of the terminal attached to stdout. The COLUMNS
+// environment variable takes precedence, and defaultTerminalWidth is used when
+// stdout is not a terminal.
+func terminalWidth() int {
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+
+	if w := ttyWidth(os.Stdout); w > 0 {
+		return w
+	}
+
+	return defaultTerminalWidth
+}
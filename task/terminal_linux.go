@@ -0,0 +1,9 @@
+package task
+
+import "syscall"
+
+// The ioctls getting and setting the terminal's attributes.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,17 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package task
+
+import (
+	"errors"
+	"os"
+)
+
+func ttyWidth(_ *os.File) int {
+	return 0
+}
+
+func makeRaw(_ *os.File) (func(), error) {
+	return nil, errors.New("reading keys from the terminal is not supported")
+}
@@ -1,70 +1,252 @@
 package task
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
 	"github.com/mgutz/ansi"
 )
 
-func newTUI(useColors bool) *TUI {
+// Style is the name of a semantic style in a Theme.
+type Style string
+
+// The styles of a Theme.
+const (
+	StyleCommand   Style = "command"
+	StyleDuration  Style = "duration"
+	StyleError     Style = "error"
+	StyleHighlight Style = "highlight"
+	StyleInfo      Style = "info"
+	StyleLowlight  Style = "lowlight"
+	StyleSuccess   Style = "success"
+	StyleTaskName  Style = "taskName"
+	StyleWarning   Style = "warning"
+)
+
+// Theme maps styles to ansi style specs such as "red+b", "208+b" or "white:19". Besides named and
+// 256-color palette colors, "#rrggbb" truecolor colors are supported and are approximated with the
+// 256-color palette when the terminal doesn't advertise truecolor support. An empty spec leaves
+// the text unstyled.
+type Theme map[Style]string
+
+// DefaultTheme returns the theme used when none is configured.
+func DefaultTheme() Theme {
+	return Theme{
+		StyleCommand:   "cyan",
+		StyleDuration:  "black+bh",
+		StyleError:     "red+b",
+		StyleHighlight: "white+bh",
+		StyleInfo:      "cyan+b",
+		StyleLowlight:  "black+bh",
+		StyleSuccess:   "green+b",
+		StyleTaskName:  "white+bh",
+		StyleWarning:   "yellow+b",
+	}
+}
+
+// LoadTheme reads a theme from a JSON file mapping style names to specs, such as
+// {"taskName": "#ff8700+b", "duration": "244"}. Styles missing from the file keep their defaults.
+func LoadTheme(path string) (Theme, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading theme: %w", err)
+	}
+
+	var specs map[Style]string
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("failed parsing theme %s: %w", path, err)
+	}
+
+	theme := DefaultTheme()
+	for style, spec := range specs {
+		if _, ok := theme[style]; !ok {
+			return nil, fmt.Errorf("unknown style %q in theme %s", style, path)
+		}
+		theme[style] = spec
+	}
+	return theme, nil
+}
+
+// WithTheme sets the theme used to color human output. A theme file named by the GOKE_THEME
+// environment variable takes precedence, letting users pick their own colors.
+func WithTheme(theme Theme) RegistryOption {
+	return func(r *Registry) {
+		r.theme = theme
+	}
+}
+
+// NewTUI makes a TUI which styles text using the theme. Styles missing from the theme use their defaults.
+func NewTUI(theme Theme) *TUI {
+	truecolor := supportsTruecolor()
+	codes := make(map[Style]string)
+	for style, spec := range DefaultTheme() {
+		if s, ok := theme[style]; ok {
+			spec = s
+		}
+		codes[style] = colorCode(spec, truecolor)
+	}
+
+	return &TUI{codes: codes}
+}
+
+func newTUI(useColors bool, theme Theme) *TUI {
 	if !useColors {
 		return nil
 	}
 
-	return &TUI{}
+	return NewTUI(theme)
 }
 
 // TUI is responsible for setting coloring information.
-type TUI struct{}
+type TUI struct {
+	codes map[Style]string
+}
 
-// Error colors the msg as an error.
-func (ui *TUI) Error(msg string) string {
-	if ui == nil {
+// Style styles the msg using the theme's spec for the style.
+func (ui *TUI) Style(style Style, msg string) string {
+	if ui == nil || msg == "" {
+		return msg
+	}
+
+	code := ui.codes[style]
+	if code == "" {
 		return msg
 	}
 
-	return ansi.Color(msg, "red+b")
+	return code + msg + ansi.Reset
+}
+
+// Command colors the msg as a command line.
+func (ui *TUI) Command(msg string) string {
+	return ui.Style(StyleCommand, msg)
+}
+
+// Duration colors the msg as a duration.
+func (ui *TUI) Duration(msg string) string {
+	return ui.Style(StyleDuration, msg)
+}
+
+// Error colors the msg as an error.
+func (ui *TUI) Error(msg string) string {
+	return ui.Style(StyleError, msg)
 }
 
 // Highlight colors the msg as a highlight.
 func (ui *TUI) Highlight(msg string) string {
-	if ui == nil {
-		return msg
-	}
-
-	return ansi.Color(msg, "white+bh")
+	return ui.Style(StyleHighlight, msg)
 }
 
 // Info colors the msg as information.
 func (ui *TUI) Info(msg string) string {
-	if ui == nil {
-		return msg
-	}
-
-	return ansi.Color(msg, "cyan+b")
+	return ui.Style(StyleInfo, msg)
 }
 
 // Lowlight colors the msg as a lowlight.
 func (ui *TUI) Lowlight(msg string) string {
-	if ui == nil {
-		return msg
-	}
-
-	return ansi.Color(msg, "black+bh")
+	return ui.Style(StyleLowlight, msg)
 }
 
 // Success colors the msg as success.
 func (ui *TUI) Success(msg string) string {
-	if ui == nil {
-		return msg
-	}
+	return ui.Style(StyleSuccess, msg)
+}
 
-	return ansi.Color(msg, "green+b")
+// TaskName colors the msg as the name of a task.
+func (ui *TUI) TaskName(msg string) string {
+	return ui.Style(StyleTaskName, msg)
 }
 
 // Warning colors the msg as warning.
 func (ui *TUI) Warning(msg string) string {
-	if ui == nil {
-		return msg
+	return ui.Style(StyleWarning, msg)
+}
+
+// useColors decides whether to color the output. An explicit -color argument wins, followed by the
+// NO_COLOR, FORCE_COLOR, CLICOLOR_FORCE and CLICOLOR conventions, before falling back to whether
+// the output is a terminal.
+func useColors(colorArg string, hasColorArg bool, isTTY bool) bool {
+	if hasColorArg {
+		return colorArg == trueString
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		return v != "0" && v != "false"
+	}
+	if v := os.Getenv("CLICOLOR_FORCE"); v != "" && v != "0" {
+		return true
 	}
+	if os.Getenv("CLICOLOR") == "0" {
+		return false
+	}
+	return isTTY
+}
+
+func supportsTruecolor() bool {
+	colorterm := os.Getenv("COLORTERM")
+	return colorterm == "truecolor" || colorterm == "24bit"
+}
 
-	return ansi.Color(msg, "yellow+b")
+var hexColor = regexp.MustCompile(`#([0-9a-fA-F]{6})`)
+
+// colorCode returns the escape sequence for the spec, replacing truecolor colors with
+// their nearest 256-color palette entries unless truecolor is supported.
+func colorCode(spec string, truecolor bool) string {
+	type rgb struct{ index, r, g, b int }
+	var colors []rgb
+	spec = hexColor.ReplaceAllStringFunc(spec, func(hex string) string {
+		v, _ := strconv.ParseUint(hex[1:], 16, 32)
+		c := rgb{r: int(v >> 16 & 0xff), g: int(v >> 8 & 0xff), b: int(v & 0xff)}
+		c.index = nearestPaletteColor(c.r, c.g, c.b)
+		colors = append(colors, c)
+		return strconv.Itoa(c.index)
+	})
+
+	code := ansi.ColorCode(spec)
+	if truecolor {
+		// the palette colors appear in the code in the same order as in the spec
+		offset := 0
+		for _, c := range colors {
+			for _, layer := range []string{"38", "48"} {
+				palette := fmt.Sprintf("%s;5;%d", layer, c.index)
+				i := strings.Index(code[offset:], palette)
+				if i < 0 || !strings.ContainsAny(code[offset+i+len(palette):][:1], ";m") {
+					continue
+				}
+				rgb := fmt.Sprintf("%s;2;%d;%d;%d", layer, c.r, c.g, c.b)
+				code = code[:offset+i] + rgb + code[offset+i+len(palette):]
+				offset += i + len(rgb)
+				break
+			}
+		}
+	}
+	return code
+}
+
+// nearestPaletteColor returns the index of the closest color in the 6x6x6 cube of the 256-color palette.
+func nearestPaletteColor(r, g, b int) int {
+	levels := []int{0, 95, 135, 175, 215, 255}
+	nearest := func(v int) int {
+		best := 0
+		for i, level := range levels {
+			if abs(level-v) < abs(levels[best]-v) {
+				best = i
+			}
+		}
+		return best
+	}
+	return 16 + 36*nearest(r) + 6*nearest(g) + nearest(b)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
 }
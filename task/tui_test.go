@@ -0,0 +1,108 @@
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUseColors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		colorArg string
+		isTTY    bool
+		expected bool
+	}{
+		{name: "terminal", isTTY: true, expected: true},
+		{name: "pipe", expected: false},
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1"}, isTTY: true, expected: false},
+		{name: "FORCE_COLOR", env: map[string]string{"FORCE_COLOR": "1"}, expected: true},
+		{name: "FORCE_COLOR=0", env: map[string]string{"FORCE_COLOR": "0"}, isTTY: true, expected: false},
+		{name: "NO_COLOR wins over FORCE_COLOR", env: map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, expected: false},
+		{name: "CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1"}, expected: true},
+		{name: "CLICOLOR=0", env: map[string]string{"CLICOLOR": "0"}, isTTY: true, expected: false},
+		{name: "-color=false", env: map[string]string{"FORCE_COLOR": "1"}, colorArg: "false", isTTY: true, expected: false},
+		{name: "-color", env: map[string]string{"NO_COLOR": "1"}, colorArg: trueString, expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range []string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR"} {
+				if v, ok := os.LookupEnv(name); ok {
+					defer os.Setenv(name, v)
+				} else {
+					defer os.Unsetenv(name)
+				}
+				os.Unsetenv(name)
+			}
+			for name, v := range tc.env {
+				os.Setenv(name, v)
+			}
+
+			actual := useColors(tc.colorArg, tc.colorArg != "", tc.isTTY)
+			if actual != tc.expected {
+				t.Fatalf("expected %v but got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestColorCode(t *testing.T) {
+	testCases := []struct {
+		spec      string
+		truecolor bool
+		expected  string
+	}{
+		{spec: "red+b", expected: "\x1b[1;31m"},
+		{spec: "208+b", expected: "\x1b[1;38;5;208m"},
+		{spec: "#ff8700+b", expected: "\x1b[1;38;5;208m"},
+		{spec: "#ff8700+b", truecolor: true, expected: "\x1b[1;38;2;255;135;0m"},
+		{spec: "white:#00005f", truecolor: true, expected: "\x1b[37;48;2;0;0;95m"},
+		{spec: "#000000:#000001", truecolor: true, expected: "\x1b[38;2;0;0;0;48;2;0;0;1m"},
+		{spec: "", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		actual := colorCode(tc.spec, tc.truecolor)
+		if actual != tc.expected {
+			t.Errorf("%q (truecolor=%v): expected %q but got %q", tc.spec, tc.truecolor, tc.expected, actual)
+		}
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	dir, err := ioutil.TempDir("", "theme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "theme.json")
+	if err := ioutil.WriteFile(path, []byte(`{"taskName": "#ff8700+b", "duration": ""}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := LoadTheme(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui := NewTUI(theme)
+	if actual := ui.TaskName("build"); actual != "\x1b[1;38;5;208mbuild\x1b[0m" && actual != "\x1b[1;38;2;255;135;0mbuild\x1b[0m" {
+		t.Errorf("unexpected task name style %q", actual)
+	}
+	if actual := ui.Duration("1s"); actual != "1s" {
+		t.Errorf("expected an unstyled duration but got %q", actual)
+	}
+	if actual := ui.Error("failed"); actual != "\x1b[1;31mfailed\x1b[0m" {
+		t.Errorf("expected the default error style but got %q", actual)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"bogus": "red"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTheme(path); err == nil {
+		t.Error("expected an error for an unknown style")
+	}
+}
@@ -11,16 +11,17 @@ import (
 )
 
 func usage(ui *TUI, fs *flag.FlagSet, registry *Registry) {
+	width := terminalWidth()
 	var buf bytes.Buffer
-	usageTemp(ui, fs, registry, 0, &buf)
+	usageTemp(ui, fs, registry, 0, width, &buf)
 	rd := bufio.NewReader(&buf)
 	maxLine := 0
 	for {
 		line, _, err := rd.ReadLine()
 		if len(line) > maxLine {
 			maxLine = len(line)
-			if maxLine > 80 {
-				maxLine = 80
+			if maxLine > width-2 {
+				maxLine = width - 2
 			}
 		}
 
@@ -29,10 +30,10 @@ func usage(ui *TUI, fs *flag.FlagSet, registry *Registry) {
 		}
 	}
 
-	usageTemp(ui, fs, registry, maxLine, os.Stdout)
+	usageTemp(ui, fs, registry, maxLine, width, os.Stdout)
 }
 
-func usageTemp(ui *TUI, fs *flag.FlagSet, registry *Registry, longestLine int, out io.Writer) {
+func usageTemp(ui *TUI, fs *flag.FlagSet, registry *Registry, longestLine int, width int, out io.Writer) {
 	fmt.Fprintln(out, ui.Highlight("USAGE")+": [tasks ...] [options ...]")
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, ui.Highlight("TASKS")+":")
@@ -66,16 +67,184 @@ func usageTemp(ui *TUI, fs *flag.FlagSet, registry *Registry, longestLine int, o
 		if len(t.Dependencies()) > 0 {
 			fmt.Fprint(out, " -> ", t.Dependencies())
 		}
+		if d := taskDeprecation(t); d != nil {
+			fmt.Fprint(out, " ", ui.Warning("(deprecated)"))
+		}
 		fmt.Fprintln(out)
-		if t.Description() != "" {
-			fmt.Fprintln(out, "       ", t.Description())
+		if len(taskAliases(t)) > 0 {
+			fmt.Fprintln(out, "       ", ui.Highlight("aliases"), "->", taskAliases(t))
+		}
+		for _, line := range wrapText(t.Description(), width-8) {
+			fmt.Fprintln(out, "       ", line)
 		}
 		if len(t.DeferredTasks()) > 0 {
 			fmt.Fprintln(out, "       ", ui.Highlight("deferred"), "->", t.DeferredTasks())
 		}
+		if len(taskFinalizedBy(t)) > 0 {
+			fmt.Fprintln(out, "       ", ui.Highlight("finalized by"), "->", taskFinalizedBy(t))
+		}
+	}
+	if len(registry.rules) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, ui.Highlight("RULES")+":")
+		for _, rl := range registry.rules {
+			fmt.Fprintln(out, "  "+ui.Info(rl.pattern))
+			for _, line := range wrapText(rl.description, width-8) {
+				fmt.Fprintln(out, "       ", line)
+			}
+		}
 	}
 	fmt.Fprintln(out)
 	fmt.Fprintln(out, "OPTIONS:")
 	fs.SetOutput(out)
 	fs.PrintDefaults()
 }
+
+func taskUsage(ui *TUI, registry *Registry, t Task, width int, out io.Writer) {
+	fmt.Fprintln(out, ui.Highlight("TASK")+":", ui.Info(t.Name()))
+	if ns := registry.taskNamespace(t); ns != "" {
+		fmt.Fprintln(out, ui.Highlight("NAMESPACE")+":", ns)
+	}
+	if len(taskAliases(t)) > 0 {
+		fmt.Fprintln(out, ui.Highlight("ALIASES")+":", strings.Join(taskAliases(t), ", "))
+	}
+	if d := taskDeprecation(t); d != nil {
+		fmt.Fprintln(out, ui.Warning("DEPRECATED")+":", d.String())
+	}
+	if t.Description() != "" {
+		fmt.Fprintln(out)
+		for _, line := range wrapText(t.Description(), width-2) {
+			fmt.Fprintln(out, " ", line)
+		}
+	}
+	if taskLongDescription(t) != "" {
+		fmt.Fprintln(out)
+		for _, paragraph := range strings.Split(strings.TrimSpace(taskLongDescription(t)), "\n") {
+			lines := wrapText(paragraph, width-2)
+			if len(lines) == 0 {
+				fmt.Fprintln(out)
+			}
+			for _, line := range lines {
+				fmt.Fprintln(out, " ", line)
+			}
+		}
+	}
+
+	if args := t.DeclaredArgs(); len(args) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, ui.Highlight("ARGS")+":")
+		for _, a := range args {
+			var attrs []string
+			switch {
+			case a.IsRequired():
+				attrs = append(attrs, "required")
+			case a.Validator != nil:
+				attrs = append(attrs, "optional, validated")
+			default:
+				attrs = append(attrs, "optional")
+			}
+			if a.Secret {
+				attrs = append(attrs, "secret")
+			}
+			// Context.Get falls back to the environment when an argument has no value
+			switch {
+			case a.Default != "":
+				attrs = append(attrs, fmt.Sprintf("default %q", maskArg(a, a.Default)))
+			case !a.IsRequired():
+				attrs = append(attrs, "env $"+a.Name)
+			}
+			fmt.Fprintf(out, "  %s (%s)\n", ui.Info(a.Name), strings.Join(attrs, ", "))
+		}
+	}
+
+	if len(taskResources(t)) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, ui.Highlight("REQUIRES")+":", strings.Join(taskResources(t), ", "))
+	}
+
+	if len(taskMatrix(t)) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, ui.Highlight("MATRIX")+":")
+		for _, axis := range taskMatrix(t) {
+			fmt.Fprintf(out, "  %s = %s\n", ui.Info(axis.Arg), strings.Join(axis.Values, ", "))
+		}
+	}
+
+	if len(t.Dependencies()) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, ui.Highlight("DEPENDENCIES")+":")
+		printDependencyTree(ui, registry, t.Dependencies(), "  ", map[string]bool{t.Name(): true}, out)
+	}
+
+	if len(taskMustRunAfter(t)) > 0 || len(taskShouldRunAfter(t)) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, ui.Highlight("RUNS AFTER")+":")
+		for _, name := range taskMustRunAfter(t) {
+			fmt.Fprintln(out, "  "+ui.Info(name))
+		}
+		for _, name := range taskShouldRunAfter(t) {
+			fmt.Fprintln(out, "  "+ui.Info(name), "(if possible)")
+		}
+	}
+
+	if len(taskFinalizedBy(t)) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, ui.Highlight("FINALIZED BY")+":")
+		for _, name := range taskFinalizedBy(t) {
+			fmt.Fprintln(out, "  "+ui.Info(name))
+		}
+	}
+
+	if len(t.DeferredTasks()) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, ui.Highlight("DEFERRED")+":")
+		for _, name := range t.DeferredTasks() {
+			fmt.Fprintln(out, "  "+ui.Info(name))
+		}
+	}
+}
+
+func printDependencyTree(ui *TUI, registry *Registry, names []string, indent string, path map[string]bool, out io.Writer) {
+	for _, name := range names {
+		dep, ok := registry.findTask(name)
+		if !ok {
+			fmt.Fprintln(out, indent+ui.Error(name), "(unknown)")
+			continue
+		}
+		if path[dep.Name()] {
+			fmt.Fprintln(out, indent+ui.Info(dep.Name()), "(cycle)")
+			continue
+		}
+
+		fmt.Fprintln(out, indent+ui.Info(dep.Name()))
+		path[dep.Name()] = true
+		printDependencyTree(ui, registry, dep.Dependencies(), indent+"  ", path, out)
+		delete(path, dep.Name())
+	}
+}
+
+// wrapText splits text into lines no longer than width, breaking on whitespace.
+// Words longer than width are placed on their own line.
+func wrapText(text string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+
+	var lines []string
+	var line strings.Builder
+	for _, word := range strings.Fields(text) {
+		if line.Len() > 0 && line.Len()+1+len(word) > width {
+			lines = append(lines, line.String())
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(word)
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+
+	return lines
+}
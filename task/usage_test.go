@@ -0,0 +1,56 @@
+package task
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	lines := wrapText("the quick brown fox jumps over the lazy dog", 10)
+	expected := []string{"the quick", "brown fox", "jumps over", "the lazy", "dog"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("expected %q, but got %q", expected, lines)
+	}
+}
+
+func TestTaskUsage(t *testing.T) {
+	registry := NewRegistry(WithAutoNamespaces(true))
+	registry.Declare("clean").Do(makeExecutor("clean", false))
+	registry.Declare("sa:fmt").Do(makeExecutor("sa:fmt", false))
+	registry.Declare("sa:lint").
+		Description("lint the packages").
+		LongDescription("Runs golint against every package.\n\nFails when any warning is reported.").
+		RequiredArg("pkg").
+		OptionalArg("verbose").
+		DefaultArg("mode", "strict").
+		DependsOn("clean", "sa:fmt").
+		Defer("clean").
+		Do(makeExecutor("sa:lint", false))
+
+	task, ok := registry.findTask("SA:LINT")
+	if !ok {
+		t.Fatal("expected to find task sa:lint")
+	}
+
+	var buf bytes.Buffer
+	taskUsage(nil, registry, task, 80, &buf)
+	out := buf.String()
+
+	for _, expected := range []string{
+		"TASK: sa:lint\n",
+		"NAMESPACE: sa\n",
+		"  lint the packages\n",
+		"  Runs golint against every package.\n\n  Fails when any warning is reported.\n",
+		"  pkg (required)\n",
+		"  verbose (optional, env $verbose)\n",
+		"  mode (optional, default \"strict\")\n",
+		"DEPENDENCIES:\n  clean\n  sa:fmt\n",
+		"DEFERRED:\n  clean\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected output to contain %q, but got:\n%s", expected, out)
+		}
+	}
+}